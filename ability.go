@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	BARRIER_TIME   = 3 //seconds the barrier shield lasts
+	BARRIER_SHIELD = 3 //hits absorbed by the barrier
+	SLOW_TIME      = 4 //seconds enemies are slowed
+	WALL_LENGTH    = 3 //tiles placed by wall ability
+)
+
+// Ability is an action that costs mana and has a cooldown
+// the input that uses it comes from the Controls of the player
+type Ability struct {
+	Name     string
+	ManaCost int
+	Cooldown float32    //seconds
+	Color    color.RGBA //color of its cooldown ring
+	cooldown utils.Timer
+	activate func(p *Player) bool //returns false if ability couldnt be used so mana isnt spent
+}
+
+func NewAbility(name string, manaCost int, cooldown float32, c color.RGBA, activate func(p *Player) bool) *Ability {
+	//timer starts at 0 so ability is ready from the start
+	return &Ability{Name: name, ManaCost: manaCost, Cooldown: cooldown, Color: c, cooldown: utils.NewTimer(0), activate: activate}
+}
+
+// abilities the player starts with
+func DefaultAbilities() []*Ability {
+	return []*Ability{
+		NewAbility("Burst", 100, 0, color.RGBA{0, 191, 255, 255}, radialBurst),
+		NewAbility("Wall", 30, 3, color.RGBA{200, 200, 200, 255}, placeWall),
+		NewAbility("Barrier", 50, 10, ShieldColor, barrier),
+		NewAbility("Slow", 60, 12, color.RGBA{255, 215, 0, 255}, timeSlow),
+	}
+}
+
+// from 0 to 1 . 1 means ability is off cooldown
+func (a *Ability) Ready() float64 {
+	if a.cooldown.Time == 0 {
+		return 1
+	}
+	return math.Min(1, float64(a.cooldown.GetCurrentTime()/a.cooldown.Time))
+}
+
+// pressed is the input bound to the ability for this player
+func (a *Ability) Update(p *Player, pressed bool) {
+	if a.Ready() < 1 {
+		a.cooldown.UpdateTimer()
+	}
+	if pressed && a.Ready() >= 1 && p.mana >= a.ManaCost && a.activate(p) {
+		p.mana -= a.ManaCost
+		a.cooldown = utils.NewTimer(a.Cooldown)
+	}
+}
+
+// the original special attack . bullets in all directions
+func radialBurst(p *Player) bool {
+	FirePattern("player", "radial", p.Pos, 0, nil)
+	return true
+}
+
+// turns a line of air tiles at the cursor to rigid facing the player
+func placeWall(p *Player) bool {
+	c := p.aimPoint()
+	dir := utils.Vec2{X: c.X - p.Pos.X, Y: c.Y - p.Pos.Y}
+	dir.NormalizeDir()
+	perp := utils.Vec2{X: -dir.Y, Y: dir.X}
+	placed := false
+	for i := range WALL_LENGTH {
+		offset := float32(i-WALL_LENGTH/2) * TILE_SIZE
+		tile := game.Tilemap.GetTile(utils.Vec2{X: c.X + perp.X*offset, Y: c.Y + perp.Y*offset})
+		if tile == nil || tile.Solid() || p.rect().Collide(tile.rect()) {
+			continue
+		}
+		tile.SetVariant(Rigid)
+		placed = true
+	}
+	return placed
+}
+
+// adds shield that blocks a few hits and is lost if not used up in time
+func barrier(p *Player) bool {
+	p.shield += BARRIER_SHIELD - p.barrierShield
+	p.barrierShield = BARRIER_SHIELD
+	p.barrier = utils.NewTimer(BARRIER_TIME)
+	return true
+}
+
+// enemies and their bullets move at half speed for a while
+func timeSlow(p *Player) bool {
+	game.slowed = true
+	game.slow = utils.NewTimer(SLOW_TIME)
+	return true
+}
+
+// checks if entity should skip this frame because of time slow
+func isSlowed(e Entity) bool {
+	if !game.slowed || game.frame%2 == 0 {
+		return false
+	}
+	switch e := e.(type) {
+	case *Bullet:
+		return e.IsEnemy()
+	case *PatternEmitter:
+		return e.Shooter != "player"
+	}
+	return e.Type() == "enemy" || e.Type() == "boss"
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hasona23/game/utils"
+)
+
+// neighbour bits of 8 bit masks . 4 bit masks use N=1 E=2 S=4 W=8
+const (
+	MaskN = 1 << iota
+	MaskNE
+	MaskE
+	MaskSE
+	MaskS
+	MaskSW
+	MaskW
+	MaskNW
+)
+
+// picks a sprite from a sheet by which neighbours are the same variant
+//
+// 4 bit sheets have 16 tiles in a 4x4 grid indexed by the mask
+// 8 bit sheets have the 47 blob tiles in an 8x6 grid ordered by blobMasks
+type Autotile struct {
+	Bits   int
+	frames []*utils.Sprite
+}
+
+// every 8 bit mask after corners without both sides are removed . 47 in total
+var blobMasks = func() []int {
+	masks := []int{}
+	for m := range 256 {
+		if m = reduceMask(m); !slices.Contains(masks, m) {
+			masks = append(masks, m)
+		}
+	}
+	slices.Sort(masks)
+	return masks
+}()
+
+// autotiles of variants with an Autotile sheet
+var autotiles = map[Variant]*Autotile{}
+
+// a corner only matters when both sides next to it are connected
+func reduceMask(m int) int {
+	for _, c := range [][3]int{{MaskNE, MaskN, MaskE}, {MaskSE, MaskS, MaskE}, {MaskSW, MaskS, MaskW}, {MaskNW, MaskN, MaskW}} {
+		if m&c[1] == 0 || m&c[2] == 0 {
+			m &^= c[0]
+		}
+	}
+	return m
+}
+
+// loads the sheets of every tile type that has one
+func LoadAutotiles() error {
+	for v, tt := range tileTypes {
+		if tt.Autotile == "" {
+			continue
+		}
+		img, _, err := ebitenutil.NewImageFromFile(tt.Autotile)
+		if err != nil {
+			return err
+		}
+		cols, rows := 4, 4
+		if tt.AutotileBits == 8 {
+			cols, rows = 8, 6
+		} else if tt.AutotileBits != 4 {
+			return fmt.Errorf("autotile %v: bits must be 4 or 8 got %v", tt.Autotile, tt.AutotileBits)
+		}
+		frames := utils.NewSpriteSheet(img, img.Bounds().Dx()/cols, img.Bounds().Dy()/rows)
+		if tt.AutotileBits == 8 && len(frames) < len(blobMasks) || len(frames) < 16 {
+			return fmt.Errorf("autotile %v: sheet has %v tiles", tt.Autotile, len(frames))
+		}
+		autotiles[v] = &Autotile{Bits: tt.AutotileBits, frames: frames}
+	}
+	return nil
+}
+
+// sprite for the tile at column x and row y of t
+func (a *Autotile) Frame(t *Tilemap, x, y int) *utils.Sprite {
+	v := t.TileAt(x, y).Variant
+	//tiles outside the map count as connected so the map border has no edges
+	same := func(dx, dy int) bool {
+		if x+dx < 0 || y+dy < 0 || x+dx >= t.Width || y+dy >= t.Height {
+			return true
+		}
+		tile := t.peekTile(x+dx, y+dy)
+		return tile == nil || tile.Variant == v
+	}
+	if a.Bits == 4 {
+		m := 0
+		for i, d := range []utils.Point{{Y: -1}, {X: 1}, {Y: 1}, {X: -1}} {
+			if same(d.X, d.Y) {
+				m |= 1 << i
+			}
+		}
+		return a.frames[m]
+	}
+	m := 0
+	for i, d := range []utils.Point{{Y: -1}, {X: 1, Y: -1}, {X: 1}, {X: 1, Y: 1}, {Y: 1}, {X: -1, Y: 1}, {X: -1}, {X: -1, Y: -1}} {
+		if same(d.X, d.Y) {
+			m |= 1 << i
+		}
+	}
+	i, _ := slices.BinarySearch(blobMasks, reduceMask(m))
+	return a.frames[i]
+}
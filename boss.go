@@ -0,0 +1,216 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/particles"
+	"github.com/hasona23/game/ui"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	BOSS_SIZE          = 48
+	BOSS_HP            = 60
+	BOSS_BULLET_DAMAGE = 15
+	BOSS_SLAM_RADIUS   = 80
+	BOSS_SLAM_CHARGE   = 0.75
+	BOSS_SLAM_DAMAGE   = 25
+	BOSS_CONTACT       = 20   //damage to a player touching the boss
+	BOSS_CONTACT_RATE  = 0.75 //seconds between contact hits
+	BOSS_SCORE         = 50
+	BOSS_MANA          = 100
+	SLAM_TRAUMA        = 0.8 //camera shake when a slam lands
+)
+
+var BossColor = color.RGBA{200, 0, 60, 255}
+
+// each phase changes how the boss moves and attacks
+type BossPhase struct {
+	Speed      float32
+	AttackRate float32 //seconds between attacks
+	SlamRate   float32 //seconds between slams . 0 means no slam
+	Pattern    string  //name of bullet pattern fired every attack
+	Spin       float64 //radians the pattern turns every attack instead of aiming at the player . 0 aims at the player
+}
+
+// phases ordered by hp left . phase i starts when hp drops below 1-i/len(phases)
+var bossPhases = []BossPhase{
+	{Speed: 0.3, AttackRate: 2.5, SlamRate: 0, Pattern: "boss_ring"},
+	{Speed: 0.4, AttackRate: 1.5, SlamRate: 6, Pattern: "boss_fan"},
+	{Speed: 0.6, AttackRate: 0.12, SlamRate: 4, Pattern: "boss_spiral", Spin: 0.35},
+}
+
+type Boss struct {
+	DynamicEntity
+	hp         int
+	phase      int
+	attack     utils.Timer
+	slam       utils.Timer
+	slamCharge utils.Timer
+	slamming   bool
+	contact    utils.Timer //time since the boss last hit a player by touching them
+	spin       float64     //current angle of spinning patterns
+	hpBar      *ui.Bar
+}
+
+func NewBoss(pos utils.Vec2) *Boss {
+	b := &Boss{
+		DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, bossPhases[0].Speed, "boss", BossColor, false},
+		hp:            BOSS_HP,
+		contact:       utils.NewTimer(BOSS_CONTACT_RATE),
+		hpBar:         ui.NewBar(int(pos.X), int(pos.Y)-8, BOSS_SIZE, 4, utils.Point{X: 1, Y: 1}, BossColor, color.Gray{60}),
+	}
+	b.hpBar.SetValueAndMax(BOSS_HP, BOSS_HP)
+	b.setPhase(0)
+	game.AddEntity(b)
+	return b
+}
+func (b *Boss) setPhase(phase int) {
+	b.phase = phase
+	b.speed = bossPhases[phase].Speed
+	b.attack = utils.NewTimer(bossPhases[phase].AttackRate)
+	b.slam = utils.NewTimer(bossPhases[phase].SlamRate)
+	b.slamCharge = utils.NewTimer(BOSS_SLAM_CHARGE)
+	b.slamming = false
+}
+func (b Boss) rect() utils.Rect {
+	return utils.NewRect(int(b.Pos.X), int(b.Pos.Y), BOSS_SIZE, BOSS_SIZE)
+}
+func (b Boss) centre() utils.Vec2 {
+	return utils.Vec2{X: b.Pos.X + BOSS_SIZE/2, Y: b.Pos.Y + BOSS_SIZE/2}
+}
+func (b Boss) Type() string {
+	return b.etype
+}
+func (b Boss) IsDestroyed() bool {
+	return b.Destroyed
+}
+func (b *Boss) Update() {
+	player := nearestPlayer(b.centre())
+
+	if phase := int(float32(len(bossPhases)) * (1 - float32(b.hp)/BOSS_HP)); phase != b.phase && phase < len(bossPhases) {
+		b.setPhase(phase)
+	}
+	//boss stands still while charging slam
+	if !b.slamming {
+		b.Dir = utils.Vec2{X: player.Pos.X - b.Pos.X, Y: player.Pos.Y - b.Pos.Y}
+		b.Dir.NormalizeDir()
+		b.walk(BOSS_SIZE)
+
+		b.attack.UpdateTimer()
+		if b.attack.Ticked() {
+			b.fire(player)
+		}
+	}
+	if bossPhases[b.phase].SlamRate > 0 {
+		if b.slamming {
+			b.slamCharge.UpdateTimer()
+			if b.slamCharge.Ticked() {
+				b.slamming = false
+				b.doSlam()
+				game.cam.Shake(SLAM_TRAUMA)
+			}
+		} else {
+			b.slam.UpdateTimer()
+			if b.slam.Ticked() {
+				b.slamming = true
+			}
+		}
+	}
+
+	//boss isnt destroyed by touching like other enemies so contact hits are spaced out
+	b.contact.UpdateTimer()
+	if b.rect().Collide(player.rect()) && b.contact.Ticked() {
+		player.TakeDamage(BOSS_CONTACT)
+	}
+	hitByBullets(b)
+	b.hpBar.SetPos(int(b.Pos.X), int(b.Pos.Y)-8)
+	b.hpBar.SetValue(b.hp)
+	if b.hp <= 0 {
+		b.die(player)
+	}
+}
+
+func (b *Boss) TakeDamage(n int) {
+	b.hp -= n
+}
+
+// rewards the player , plays victory fanfare and starts the next wave
+func (b *Boss) die(player *Player) {
+	b.Destroyed = true
+	game.score += BOSS_SCORE
+	player.mana += BOSS_MANA
+	DropLoot("boss", b.centre())
+	game.bossFight = false
+	game.nextWave()
+	game.showBanner("BOSS DEFEATED", 3)
+	playFanfare()
+	for _, c := range []color.RGBA{BossColor, {255, 215, 0, 255}, {255, 255, 255, 255}, {0, 191, 255, 255}} {
+		particlesSystem := particles.NewParticleSystem(
+			particles.WithArea(utils.NewRect(int(b.Pos.X), int(b.Pos.Y), BOSS_SIZE, BOSS_SIZE)),
+			particles.WithMotionType(particles.Outward),
+			particles.WithShrinking(0.05),
+			particles.WithGravity(0.05),
+			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(c), particles.WithScale(6),
+				particles.WithSpeed(2))))
+		particlesSystem.Spawn(20)
+		game.particles = append(game.particles, particlesSystem)
+	}
+}
+
+// fires the bullet pattern of current phase aimed at the player
+func (b *Boss) fire(player *Player) {
+	c := b.centre()
+	aim := math.Atan2(float64(player.Pos.Y-c.Y), float64(player.Pos.X-c.X))
+	if spin := bossPhases[b.phase].Spin; spin != 0 {
+		b.spin += spin
+		aim = b.spin
+	}
+	FirePattern("boss", bossPhases[b.phase].Pattern, utils.Vec2{X: c.X - BULLET_SIZE/2, Y: c.Y - BULLET_SIZE/2}, aim, func(bullet *Bullet) {
+		bullet.color = BossColor
+		bullet.damage = BOSS_BULLET_DAMAGE
+	})
+}
+
+// turns tiles around the boss rigid and damages every player close
+func (b *Boss) doSlam() {
+	c := b.centre()
+	players := game.livingPlayers()
+	for _, tile := range game.Tilemap.TilesInRadius(c, BOSS_SLAM_RADIUS) {
+		//dont bury a player inside a tile
+		if tile.Solid() || slices.ContainsFunc(players, func(p *Player) bool { return p.rect().Collide(tile.rect()) }) {
+			continue
+		}
+		tile.SetVariant(Rigid)
+	}
+	for _, player := range players {
+		pc := utils.Vec2{X: player.Pos.X + PLAYER_RECT_SIZE/2 - c.X, Y: player.Pos.Y + PLAYER_RECT_SIZE/2 - c.Y}
+		if pc.Length() <= BOSS_SLAM_RADIUS {
+			player.TakeDamage(BOSS_SLAM_DAMAGE)
+		}
+	}
+	particlesSystem := particles.NewParticleSystem(
+		particles.WithArea(utils.NewRect(int(c.X)-BOSS_SLAM_RADIUS/2, int(c.Y)-BOSS_SLAM_RADIUS/2, BOSS_SLAM_RADIUS, BOSS_SLAM_RADIUS)),
+		particles.WithMotionType(particles.Outward),
+		particles.WithShrinking(0.1),
+		particles.WithModelParticle(*particles.NewParticle(particles.WithColor(color.Black), particles.WithScale(8),
+			particles.WithSpeed(1.5))))
+	particlesSystem.Spawn(30)
+	game.particles = append(game.particles, particlesSystem)
+}
+
+func (b Boss) Draw(screen *ebiten.Image) {
+	c := b.centre()
+	if b.slamming {
+		//circle grows to the slam radius while charging
+		progress := b.slamCharge.GetCurrentTime() / b.slamCharge.Time
+		game.cam.StrokeCircle(screen, c.X, c.Y, BOSS_SLAM_RADIUS*progress, 2, BossColor)
+	}
+	game.cam.FillRect(screen, b.Pos.X, b.Pos.Y, BOSS_SIZE, BOSS_SIZE, b.color)
+}
+func (b Boss) DrawWorldUI(screen *ebiten.Image) {
+	b.hpBar.DrawCam(screen, game.cam)
+}
@@ -4,7 +4,6 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hasona23/game/particles"
 	"github.com/hasona23/game/utils"
 )
@@ -12,6 +11,10 @@ import (
 const (
 	BULLET_SIZE     = 8
 	BULLET_LIFETIME = 4
+	BULLET_DAMAGE   = 30 //damage enemy bullets do to the player
+	PLAYER_DAMAGE   = 1  //damage player bullets do to enemies
+
+	EXPLOSION_TRAUMA = 0.5 //camera shake of explosions
 )
 
 type Bullet struct {
@@ -19,6 +22,11 @@ type Bullet struct {
 	DynamicEntity
 	lifeTime    utils.Timer
 	currentTile *Tile
+	damage      int
+	pierce      int             //how many more enemies the bullet can go through
+	hits        map[Entity]bool //enemies already hit so piercing bullets dont hit them twice
+	explosion   float32         //radius of area damage when destroyed . 0 means no explosion
+	dig         float32         //radius of tiles turned to air when hitting rigid tile . 0 means only that tile
 }
 
 func NewBullet(Shooter string, pos, dir utils.Vec2, speed float32) *Bullet {
@@ -27,10 +35,20 @@ func NewBullet(Shooter string, pos, dir utils.Vec2, speed float32) *Bullet {
 		Shooter:       Shooter,
 		DynamicEntity: DynamicEntity{pos, dir, speed, "bullet", color.RGBA{0, 191, 255, 255}, false},
 		lifeTime:      utils.NewTimer(BULLET_LIFETIME),
+		damage:        BULLET_DAMAGE,
+		hits:          make(map[Entity]bool),
+	}
+	if !b.IsEnemy() {
+		b.damage = PLAYER_DAMAGE
 	}
 	game.AddEntity(b)
 	return b
 }
+
+// bullets not shot by the player hurt the player and dont dig tiles
+func (b Bullet) IsEnemy() bool {
+	return b.Shooter != "player"
+}
 func (b Bullet) Type() string {
 	return b.etype
 }
@@ -53,19 +71,19 @@ func (b *Bullet) Update() {
 	b.Pos.Y += b.Dir.Y * b.speed
 	b.lifeTime.UpdateTimer()
 	if b.lifeTime.Ticked() {
-		b.Destroyed = true
+		b.destroy()
 	}
 	b.handleCollisions()
 	for _, e := range game.entities["bullet"] {
 		b2 := e.(*Bullet)
-		if b.rect().Collide(b2.rect()) && b2.Shooter != b.Shooter {
-			b.Destroyed = true
-			b2.Destroyed = true
+		if b.rect().Collide(b2.rect()) && b2.IsEnemy() != b.IsEnemy() {
+			b.destroy()
+			b2.destroy()
 		}
 	}
 }
 func (b Bullet) Draw(screen *ebiten.Image) {
-	vector.DrawFilledRect(screen, b.Pos.X+game.cam.X, b.Pos.Y+game.cam.Y, BULLET_SIZE, BULLET_SIZE, b.color, false)
+	game.cam.FillRect(screen, b.Pos.X, b.Pos.Y, BULLET_SIZE, BULLET_SIZE, b.color)
 
 }
 
@@ -73,13 +91,78 @@ func (b Bullet) rect() utils.Rect {
 	return utils.NewRect(int(b.Pos.X), int(b.Pos.Y), BULLET_SIZE, BULLET_SIZE)
 }
 func (b *Bullet) handleCollisions() {
-	if b.Shooter == "sniper" {
+	if b.IsEnemy() {
+		return
+	}
+	if tile := game.Tilemap.GetTile(b.Pos); tile != nil && tile.Solid() && b.rect().Collide(tile.rect()) {
+		//tiles that survive the hit stop the bullet
+		if !tile.Dig(b.damage) {
+			b.destroy()
+		}
+		if b.dig > 0 {
+			digArea(b.Pos, b.dig)
+		}
+	}
+
+}
+
+// registers hit on target. returns false if target was already hit by this bullet
+// bullet is destroyed unless it can still pierce
+func (b *Bullet) hit(target Entity) bool {
+	if b.hits[target] {
+		return false
+	}
+	b.hits[target] = true
+	if b.pierce > 0 {
+		b.pierce--
+	} else {
+		b.destroy()
+	}
+	return true
+}
+
+// marks bullet as destroyed and explodes if it has area damage
+func (b *Bullet) destroy() {
+	if b.Destroyed {
 		return
 	}
-	if tile := game.Tilemap.GetTile(b.Pos); tile != nil && tile.Variant == Rigid &&
-		b.rect().Collide(utils.NewRect(int(tile.X), int(tile.Y), TILE_SIZE, TILE_SIZE)) {
-		tile.Variant = Air
-		tile.Color = color.White
+	b.Destroyed = true
+	if b.explosion > 0 {
+		explode(b.Pos, b.explosion, b.damage)
+	}
+}
+
+// damages all enemies in radius and digs the tiles
+func explode(pos utils.Vec2, radius float32, damage int) {
+	for _, etype := range []string{"enemy", "boss"} {
+		for _, e := range game.entities[etype] {
+			d, ok := e.(Damageable)
+			if !ok {
+				continue
+			}
+			x, y := d.rect().Centre()
+			if (utils.Vec2{X: float32(x) - pos.X, Y: float32(y) - pos.Y}).Length() <= radius {
+				d.TakeDamage(damage)
+			}
+		}
 	}
+	digArea(pos, radius)
+	game.cam.Shake(EXPLOSION_TRAUMA)
+	particlesSystem := particles.NewParticleSystem(
+		particles.WithArea(utils.NewRect(int(pos.X-radius/2), int(pos.Y-radius/2), int(radius), int(radius))),
+		particles.WithMotionType(particles.Outward),
+		particles.WithShrinking(0.1),
+		particles.WithModelParticle(*particles.NewParticle(particles.WithColor(color.RGBA{255, 140, 0, 255}), particles.WithScale(8),
+			particles.WithSpeed(1.5))))
+	particlesSystem.Spawn(25)
+	game.particles = append(game.particles, particlesSystem)
+}
 
+// digs out diggable tiles with centre inside radius
+func digArea(pos utils.Vec2, radius float32) {
+	for _, tile := range game.Tilemap.TilesInRadius(pos, radius) {
+		if tile.Solid() {
+			tile.Break()
+		}
+	}
 }
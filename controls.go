@@ -0,0 +1,167 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hasona23/game/netplay"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	STICK_DEADZONE = 0.3
+	AIM_DISTANCE   = 64 //pixels ahead of the player aimed at without a mouse
+)
+
+// input of one player from the keyboard , a standard gamepad or the network
+type Controls struct {
+	Up, Down, Left, Right ebiten.Key
+	Fire, Dash            ebiten.Key
+	Weapons               []ebiten.Key //key for each weapon slot
+	Abilities             []ebiten.Key //key for each ability
+	Mouse                 bool         //aim with the cursor , fire with left click and switch weapons with the wheel
+	Gamepad               bool         //use gamepad GamepadID instead of keys
+	GamepadID             ebiten.GamepadID
+	Remote                *netplay.Input //input of an online player set by the server every tick . used instead of everything else
+}
+
+var FirstControls = Controls{Up: ebiten.KeyW, Down: ebiten.KeyS, Left: ebiten.KeyA, Right: ebiten.KeyD,
+	Fire: ebiten.KeyE, Dash: ebiten.KeySpace,
+	Weapons:   []ebiten.Key{ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4},
+	Abilities: []ebiten.Key{ebiten.KeyQ, ebiten.KeyF, ebiten.KeyR, ebiten.KeyC},
+	Mouse:     true,
+}
+
+// second player aims where they move
+var SecondControls = Controls{Up: ebiten.KeyArrowUp, Down: ebiten.KeyArrowDown, Left: ebiten.KeyArrowLeft, Right: ebiten.KeyArrowRight,
+	Fire: ebiten.KeyEnter, Dash: ebiten.KeyShiftRight,
+	Weapons:   []ebiten.Key{ebiten.Key7, ebiten.Key8, ebiten.Key9, ebiten.Key0},
+	Abilities: []ebiten.Key{ebiten.KeyU, ebiten.KeyI, ebiten.KeyO, ebiten.KeyP},
+}
+
+// gamepad buttons for abilities in order
+var gamepadAbilities = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightLeft, ebiten.StandardGamepadButtonRightTop,
+	ebiten.StandardGamepadButtonRightRight, ebiten.StandardGamepadButtonFrontBottomLeft}
+
+// controls for the second player . uses the first gamepad if one is connected
+func secondPlayerControls() Controls {
+	c := SecondControls
+	if ids := ebiten.AppendGamepadIDs(nil); len(ids) > 0 && ebiten.IsStandardGamepadLayoutAvailable(ids[0]) {
+		c.Gamepad = true
+		c.GamepadID = ids[0]
+	}
+	return c
+}
+
+// direction the player wants to move in . each axis is -1 , 0 or 1
+func (c Controls) Move() (x, y float32) {
+	if c.Remote != nil {
+		return float32(sign(int(c.Remote.MoveX))), float32(sign(int(c.Remote.MoveY)))
+	}
+	if c.Gamepad {
+		sx := ebiten.StandardGamepadAxisValue(c.GamepadID, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		sy := ebiten.StandardGamepadAxisValue(c.GamepadID, ebiten.StandardGamepadAxisLeftStickVertical)
+		return float32(deadzone(sx)), float32(deadzone(sy))
+	}
+	if ebiten.IsKeyPressed(c.Up) {
+		y--
+	}
+	if ebiten.IsKeyPressed(c.Down) {
+		y++
+	}
+	if ebiten.IsKeyPressed(c.Left) {
+		x--
+	}
+	if ebiten.IsKeyPressed(c.Right) {
+		x++
+	}
+	return x, y
+}
+func deadzone(v float64) float64 {
+	if math.Abs(v) < STICK_DEADZONE {
+		return 0
+	}
+	return math.Copysign(1, v)
+}
+func (c Controls) FirePressed() bool {
+	if c.Remote != nil {
+		return c.Remote.Pressed(netplay.Fire)
+	}
+	if c.Gamepad {
+		return ebiten.IsStandardGamepadButtonPressed(c.GamepadID, ebiten.StandardGamepadButtonFrontBottomRight)
+	}
+	return c.Mouse && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || ebiten.IsKeyPressed(c.Fire)
+}
+func (c Controls) DashPressed() bool {
+	if c.Remote != nil {
+		return c.Remote.Pressed(netplay.Dash)
+	}
+	if c.Gamepad {
+		return inpututil.IsStandardGamepadButtonJustPressed(c.GamepadID, ebiten.StandardGamepadButtonRightBottom)
+	}
+	return inpututil.IsKeyJustPressed(c.Dash)
+}
+func (c Controls) AbilityPressed(i int) bool {
+	if c.Remote != nil {
+		return c.Remote.Pressed(netplay.AbilityButton(i))
+	}
+	if c.Gamepad {
+		return i < len(gamepadAbilities) && inpututil.IsStandardGamepadButtonJustPressed(c.GamepadID, gamepadAbilities[i])
+	}
+	return i < len(c.Abilities) && inpututil.IsKeyJustPressed(c.Abilities[i])
+}
+
+// weapon slot after input . bumpers or wheel cycle and keys pick a slot
+func (c Controls) SwitchWeapon(current, count int) int {
+	if c.Remote != nil {
+		if slot := int(c.Remote.Weapon); slot >= 0 && slot < count {
+			return slot
+		}
+		return current
+	}
+	next := 0
+	if c.Gamepad {
+		if inpututil.IsStandardGamepadButtonJustPressed(c.GamepadID, ebiten.StandardGamepadButtonFrontTopRight) {
+			next = 1
+		} else if inpututil.IsStandardGamepadButtonJustPressed(c.GamepadID, ebiten.StandardGamepadButtonFrontTopLeft) {
+			next = -1
+		}
+		return (current + next + count) % count
+	}
+	for i, key := range c.Weapons {
+		if i < count && inpututil.IsKeyJustPressed(key) {
+			return i
+		}
+	}
+	if c.Mouse {
+		if _, dy := ebiten.Wheel(); dy > 0 {
+			next = 1
+		} else if dy < 0 {
+			next = -1
+		}
+	}
+	return (current + next + count) % count
+}
+
+// point in the world the player aims at
+// cursor with a mouse , right stick on a gamepad and the last move direction otherwise
+func (p *Player) aimPoint() utils.Vec2 {
+	if p.controls.Remote != nil {
+		return utils.Vec2{X: p.controls.Remote.AimX, Y: p.controls.Remote.AimY}
+	}
+	if p.controls.Mouse {
+		return cursorWorldPos()
+	}
+	dir := p.facing
+	if p.controls.Gamepad {
+		sx := ebiten.StandardGamepadAxisValue(p.controls.GamepadID, ebiten.StandardGamepadAxisRightStickHorizontal)
+		sy := ebiten.StandardGamepadAxisValue(p.controls.GamepadID, ebiten.StandardGamepadAxisRightStickVertical)
+		if math.Hypot(sx, sy) > STICK_DEADZONE {
+			dir = utils.Vec2{X: float32(sx), Y: float32(sy)}
+			dir.NormalizeDir()
+		}
+	}
+	c := p.centre()
+	return utils.Vec2{X: c.X + dir.X*AIM_DISTANCE, Y: c.Y + dir.Y*AIM_DISTANCE}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Normal
+	Hard
+)
+
+// tuning values that change with difficulty
+type DifficultySettings struct {
+	SniperAccuracy   float32 //0 aims at where the player is , 1 leads the player perfectly
+	SniperSpread     float32 //max random error added to sniper shots in radians
+	SniperChargeTime float32 //seconds the aim line shows before the shot
+}
+
+var difficulties = map[Difficulty]DifficultySettings{
+	Easy:   {SniperAccuracy: 0.25, SniperSpread: 0.15, SniperChargeTime: 1.25},
+	Normal: {SniperAccuracy: 0.6, SniperSpread: 0.08, SniperChargeTime: 1},
+	Hard:   {SniperAccuracy: 1, SniperSpread: 0.02, SniperChargeTime: 0.75},
+}
+
+func (d Difficulty) Settings() DifficultySettings {
+	return difficulties[d]
+}
+
+var difficultyNames = []string{"easy", "normal", "hard"}
+
+func (d Difficulty) String() string {
+	return difficultyNames[d]
+}
+
+// difficulty by name as used by the -difficulty flag
+func ParseDifficulty(name string) (Difficulty, error) {
+	for i, n := range difficultyNames {
+		if n == name {
+			return Difficulty(i), nil
+		}
+	}
+	return Normal, fmt.Errorf("unknown difficulty %q", name)
+}
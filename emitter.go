@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/patterns"
+	"github.com/hasona23/game/utils"
+)
+
+var bulletPatterns map[string]patterns.Pattern
+
+// PatternEmitter fires the shots of a pattern over time then destroys itself
+type PatternEmitter struct {
+	Shooter string
+	Pos     utils.Vec2
+	shots   []patterns.Shot
+	elapsed float32
+	style   func(b *Bullet) //changes bullets after being fired like color and damage
+}
+
+// fires the pattern with the given name from pos aimed at aim (radians)
+// style can be nil
+func FirePattern(shooter, name string, pos utils.Vec2, aim float64, style func(b *Bullet)) *PatternEmitter {
+	p, ok := bulletPatterns[name]
+	if !ok {
+		log.Printf("FirePattern: no pattern with name %v", name)
+		return nil
+	}
+	e := &PatternEmitter{Shooter: shooter, Pos: pos, shots: p.Shots(aim), style: style}
+	e.fire()
+	if len(e.shots) > 0 {
+		game.AddEntity(e)
+	}
+	return e
+}
+
+// fires all shots whose delay has passed
+func (e *PatternEmitter) fire() {
+	for len(e.shots) > 0 && e.shots[0].Delay <= e.elapsed {
+		shot := e.shots[0]
+		e.shots = e.shots[1:]
+		b := NewBullet(e.Shooter, e.Pos, utils.Vec2{X: float32(math.Cos(shot.Angle)), Y: float32(math.Sin(shot.Angle))}, shot.Speed)
+		if e.style != nil {
+			e.style(b)
+		}
+	}
+}
+func (e *PatternEmitter) Update() {
+	e.elapsed += 1 / float32(ebiten.TPS())
+	e.fire()
+}
+func (e PatternEmitter) Draw(screen *ebiten.Image) {}
+func (e PatternEmitter) IsDestroyed() bool {
+	return len(e.shots) == 0
+}
+func (e PatternEmitter) Type() string {
+	return "emitter"
+}
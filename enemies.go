@@ -2,9 +2,10 @@ package main
 
 import (
 	"image/color"
+	"math"
+	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hasona23/game/particles"
 	"github.com/hasona23/game/utils"
 )
@@ -12,19 +13,35 @@ import (
 const (
 	ENEMY_SIZE         = 16
 	ENEMY_SPEED        = 1
-	ENEMY_BULLET_SPEED = 1.5
+	SNIPER_PATTERN     = "sniper" //bullet pattern of sniper shots . its speed is used to lead the player
+	SNIPER_FIRERATE    = 3
+	SNIPER_AIM_WIDTH   = 2
+	DIGGER_SPEED       = 0.5
+	DIGGER_HP          = 3
+	DIGGER_TRAIL_RATE  = 0.15
+	SPLITTER_HP        = 2
+	SHIELDER_SPEED     = 0.6
+	SHIELDER_HP        = 2
+	SHIELDER_TURN_RATE = 1.2         //radians per second
+	SHIELD_ARC         = math.Pi / 3 //half of the angle covered by the shield
 )
 
 var BomberColor = color.RGBA{255, 0, 0, 255}
 var SniperColor = color.RGBA{255, 240, 120, 255}
+var DiggerColor = color.RGBA{140, 90, 40, 255}
+var SplitterColor = color.RGBA{255, 120, 0, 255}
+var ShielderColor = color.RGBA{60, 160, 90, 255}
+var ShieldColor = color.RGBA{120, 220, 255, 255}
 
 type Bomber struct {
 	DynamicEntity
+	size float32
 }
 
 func NewBomber(pos utils.Vec2) *Bomber {
 	e := &Bomber{
 		DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, ENEMY_SPEED, "enemy", BomberColor, false},
+		size:          ENEMY_SIZE,
 	}
 	game.AddEntity(e)
 	return e
@@ -39,7 +56,7 @@ func (e Bomber) IsDestroyed() bool {
 			particles.WithArea(utils.NewRect(int(e.Pos.X-8), int(e.Pos.Y-8), 16, 16)),
 			particles.WithMotionType(particles.Outward),
 			particles.WithShrinking(0.075),
-			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(e.color), particles.WithScale(ENEMY_SIZE/2),
+			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(e.color), particles.WithScale(e.size/2),
 				particles.WithSpeed(1))))
 		particlesSystem.Spawn(10)
 		game.particles = append(game.particles, particlesSystem)
@@ -48,46 +65,51 @@ func (e Bomber) IsDestroyed() bool {
 	return false
 }
 func (e *Bomber) Update() {
-	player := game.entities["player"][0].(*Player)
+	player := nearestPlayer(e.Pos)
 
 	e.Dir = utils.Vec2{X: player.Pos.X - e.Pos.X, Y: player.Pos.Y - e.Pos.Y}
 	e.Dir.NormalizeDir()
-	e.Pos.X += e.Dir.X * e.speed
-	e.Pos.Y += e.Dir.Y * e.speed
+	e.walk(e.size)
 
 	if e.rect().Collide(player.rect()) {
 		e.Destroyed = true
-		player.hp -= 20
-	}
-	for _, b := range game.entities["bullet"] {
-		if b.(*Bullet).rect().Collide(e.rect()) {
-			e.Destroyed = true
-			b.(*Bullet).Destroyed = true
-			game.score++
-			game.entities["player"][0].(*Player).mana += 25
-		}
+		player.TakeDamage(20)
 	}
+	hitByBullets(e)
 	tile := game.Tilemap.GetTile(e.Pos)
-	if tile.Variant == Air && e.rect().Collide(utils.NewRect(int(tile.X), int(tile.Y), TILE_SIZE, TILE_SIZE)) {
-		tile.Variant = Rigid
-		tile.Color = color.Black
+	if tile != nil && tile.Variant == Air && e.rect().Collide(tile.rect()) {
+		tile.SetVariant(Rigid)
 	}
 
 }
+func (e *Bomber) TakeDamage(n int) {
+	if !e.Destroyed {
+		e.Destroyed = true
+		game.score++
+		nearestPlayer(e.Pos).mana += 25
+		DropLoot("bomber", e.Pos)
+	}
+}
 func (e Bomber) rect() utils.Rect {
-	return utils.NewRect(int(e.Pos.X), int(e.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)
+	return utils.NewRect(int(e.Pos.X), int(e.Pos.Y), int(e.size), int(e.size))
 }
 func (e *Bomber) Draw(screen *ebiten.Image) {
-	vector.DrawFilledRect(screen, e.Pos.X+game.cam.X, e.Pos.Y+game.cam.Y, ENEMY_SIZE, ENEMY_SIZE, e.color, false)
+	game.cam.FillRect(screen, e.Pos.X, e.Pos.Y, e.size, e.size, e.color)
 }
 
 type Sniper struct {
 	DynamicEntity
 	fireRate utils.Timer
+	charge   utils.Timer //aim line is shown while charging then the shot is fired
+	charging bool
+	aim      utils.Vec2 //point the sniper is aiming at
 }
 
 func NewSniper(pos utils.Vec2) *Sniper {
-	s := &Sniper{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, 0, "enemy", SniperColor, false}, fireRate: utils.NewTimer(3)}
+	s := &Sniper{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, 0, "enemy", SniperColor, false},
+		fireRate: utils.NewTimer(SNIPER_FIRERATE),
+		charge:   utils.NewTimer(game.difficulty.Settings().SniperChargeTime),
+	}
 	game.AddEntity(s)
 	return s
 }
@@ -113,28 +135,357 @@ func (s Sniper) IsDestroyed() bool {
 	return false
 }
 func (s *Sniper) Update() {
-	player := game.entities["player"][0].(*Player)
-	s.fireRate.UpdateTimer()
-	if s.fireRate.Ticked() {
-		b := NewBullet("sniper", s.Pos, utils.Vec2{X: float32(player.Pos.X) - (s.Pos.X), Y: float32(player.Pos.Y) - (s.Pos.Y)}, ENEMY_BULLET_SPEED)
-		b.color = s.color
-		b.lifeTime = utils.NewTimer(BULLET_LIFETIME * 2)
+	player := nearestPlayer(s.Pos)
+	if s.charging {
+		s.aim = s.predictTarget(player)
+		s.charge.UpdateTimer()
+		if s.charge.Ticked() {
+			s.charging = false
+			s.shoot()
+		}
+	} else {
+		s.fireRate.UpdateTimer()
+		if s.fireRate.Ticked() {
+			s.charging = true
+			s.aim = s.predictTarget(player)
+		}
 	}
 	if s.rect().Collide(player.rect()) {
 		s.Destroyed = true
-		player.hp -= 20
+		player.TakeDamage(20)
 	}
-	for _, b := range game.entities["bullet"] {
-		if b.(*Bullet).rect().Collide(s.rect()) && b.(*Bullet).Shooter != "sniper" {
-			s.Destroyed = true
-			b.(*Bullet).Destroyed = true
-			game.score++
-			game.entities["player"][0].(*Player).mana += 25
+	hitByBullets(s)
+}
+func (s *Sniper) TakeDamage(n int) {
+	if !s.Destroyed {
+		s.Destroyed = true
+		game.score++
+		nearestPlayer(s.Pos).mana += 25
+		DropLoot("sniper", s.Pos)
+	}
+}
+
+// returns where the player will be when the bullet reaches him
+// accuracy from difficulty decides how much of the player velocity is taken into account
+func (s Sniper) predictTarget(player *Player) utils.Vec2 {
+	toPlayer := utils.Vec2{X: player.Pos.X - s.Pos.X, Y: player.Pos.Y - s.Pos.Y}
+	vel := player.vel
+	speed := bulletPatterns[SNIPER_PATTERN].Speed
+	// solve |toPlayer + vel*t| = speed*t for the time of impact t
+	a := vel.X*vel.X + vel.Y*vel.Y - speed*speed
+	b := 2 * (toPlayer.X*vel.X + toPlayer.Y*vel.Y)
+	c := toPlayer.X*toPlayer.X + toPlayer.Y*toPlayer.Y
+	t := float32(0)
+	if math.Abs(float64(a)) < 0.0001 {
+		if b != 0 {
+			t = -c / b
+		}
+	} else if disc := b*b - 4*a*c; disc >= 0 {
+		sqrtDisc := float32(math.Sqrt(float64(disc)))
+		t1 := (-b - sqrtDisc) / (2 * a)
+		t2 := (-b + sqrtDisc) / (2 * a)
+		t = t1
+		if t1 < 0 || (t2 > 0 && t2 < t1) {
+			t = t2
 		}
 	}
+	t = float32(math.Max(0, float64(t))) * game.difficulty.Settings().SniperAccuracy
+	return utils.Vec2{X: player.Pos.X + vel.X*t, Y: player.Pos.Y + vel.Y*t}
+}
 
+// fires at the aimed point with a random error based on difficulty
+func (s *Sniper) shoot() {
+	spread := game.difficulty.Settings().SniperSpread
+	angle := math.Atan2(float64(s.aim.Y-s.Pos.Y), float64(s.aim.X-s.Pos.X)) + float64((rand.Float32()*2-1)*spread)
+	FirePattern("sniper", SNIPER_PATTERN, s.Pos, angle, func(b *Bullet) {
+		b.color = s.color
+		b.lifeTime = utils.NewTimer(BULLET_LIFETIME * 2)
+	})
 }
 
 func (s Sniper) Draw(screen *ebiten.Image) {
-	vector.DrawFilledRect(screen, s.Pos.X+game.cam.X, s.Pos.Y+game.cam.Y, ENEMY_SIZE, ENEMY_SIZE, s.color, false)
+	if s.charging {
+		//aim line gets more visible the closer the sniper is to shooting
+		progress := s.charge.GetCurrentTime() / s.charge.Time
+		c := color.RGBA{SniperColor.R, SniperColor.G, SniperColor.B, uint8(55 + 200*math.Min(1, float64(progress)))}
+		x, y := s.Pos.X+BULLET_SIZE/2, s.Pos.Y+BULLET_SIZE/2
+		game.cam.StrokeLine(screen, x, y, s.aim.X+BULLET_SIZE/2, s.aim.Y+BULLET_SIZE/2, SNIPER_AIM_WIDTH*progress, c)
+	}
+	game.cam.FillRect(screen, s.Pos.X, s.Pos.Y, ENEMY_SIZE, ENEMY_SIZE, s.color)
+}
+
+// Digger tunnels through rigid tiles towards the player leaving air behind
+type Digger struct {
+	DynamicEntity
+	hp    int
+	trail utils.Timer
+}
+
+func NewDigger(pos utils.Vec2) *Digger {
+	d := &Digger{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, DIGGER_SPEED, "enemy", DiggerColor, false},
+		hp:    DIGGER_HP,
+		trail: utils.NewTimer(DIGGER_TRAIL_RATE),
+	}
+	game.AddEntity(d)
+	return d
+}
+func (d Digger) rect() utils.Rect {
+	return utils.NewRect(int(d.Pos.X), int(d.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)
+}
+func (d Digger) Type() string {
+	return d.etype
+}
+func (d Digger) IsDestroyed() bool {
+	if d.Destroyed {
+		particlesSystem := particles.NewParticleSystem(
+			particles.WithArea(utils.NewRect(int(d.Pos.X-8), int(d.Pos.Y-8), 16, 16)),
+			particles.WithMotionType(particles.Outward),
+			particles.WithShrinking(0.05),
+			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(d.color), particles.WithScale(ENEMY_SIZE/2),
+				particles.WithSpeed(0.75))))
+		particlesSystem.Spawn(16)
+		game.particles = append(game.particles, particlesSystem)
+		return true
+	}
+	return false
+}
+func (d *Digger) Update() {
+	player := nearestPlayer(d.Pos)
+
+	d.Dir = utils.Vec2{X: player.Pos.X - d.Pos.X, Y: player.Pos.Y - d.Pos.Y}
+	d.Dir.NormalizeDir()
+	d.tunnel()
+
+	if d.rect().Collide(player.rect()) {
+		d.Destroyed = true
+		player.TakeDamage(30)
+	}
+	hitByBullets(d)
+	d.trail.UpdateTimer()
+	if d.trail.Ticked() {
+		particlesSystem := particles.NewParticleSystem(
+			particles.WithArea(utils.NewRect(int(d.Pos.X), int(d.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)),
+			particles.WithMotionType(particles.RandomDirections),
+			particles.WithShrinking(0.1),
+			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(d.color), particles.WithScale(4),
+				particles.WithSpeed(0.3))))
+		particlesSystem.Spawn(4)
+		game.particles = append(game.particles, particlesSystem)
+	}
+}
+
+// walks digging out every solid tile it moves into
+// tiles that cant be dug stop it on that axis so it slides along them instead of passing through
+func (d *Digger) tunnel() {
+	speed := d.speed * speedAt(utils.Vec2{X: d.Pos.X + ENEMY_SIZE/2, Y: d.Pos.Y + ENEMY_SIZE/2})
+	d.Pos.X += d.Dir.X * speed
+	if !d.dig() {
+		d.Pos.X -= d.Dir.X * speed
+	}
+	d.Pos.Y += d.Dir.Y * speed
+	if !d.dig() {
+		d.Pos.Y -= d.Dir.Y * speed
+	}
+}
+
+// breaks every solid tile under the digger . false if one of them cant be dug
+func (d *Digger) dig() bool {
+	dug := true
+	for _, tile := range game.Tilemap.TilesInRect(d.rect()) {
+		if tile.Solid() && !tile.Break() {
+			dug = false
+		}
+	}
+	return dug
+}
+func (d *Digger) TakeDamage(n int) {
+	d.hp -= n
+	if d.hp <= 0 && !d.Destroyed {
+		d.Destroyed = true
+		game.score += 3
+		nearestPlayer(d.Pos).mana += 40
+		DropLoot("digger", d.Pos)
+	}
+}
+func (d Digger) Draw(screen *ebiten.Image) {
+	game.cam.FillRect(screen, d.Pos.X, d.Pos.Y, ENEMY_SIZE, ENEMY_SIZE, d.color)
+	//shows how much hp is left as a darker core that shrinks
+	core := float32(ENEMY_SIZE/2) * float32(d.hp) / DIGGER_HP
+	game.cam.FillRect(screen, d.Pos.X+ENEMY_SIZE/2-core/2, d.Pos.Y+ENEMY_SIZE/2-core/2, core, core, color.RGBA{70, 45, 20, 255})
+}
+
+// Splitter breaks into two smaller and faster bombers when killed
+type Splitter struct {
+	DynamicEntity
+	hp int
+}
+
+func NewSplitter(pos utils.Vec2) *Splitter {
+	sp := &Splitter{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, ENEMY_SPEED * 0.75, "enemy", SplitterColor, false}, hp: SPLITTER_HP}
+	game.AddEntity(sp)
+	return sp
+}
+func (sp Splitter) rect() utils.Rect {
+	return utils.NewRect(int(sp.Pos.X), int(sp.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)
+}
+func (sp Splitter) Type() string {
+	return sp.etype
+}
+func (sp Splitter) IsDestroyed() bool {
+	if sp.Destroyed {
+		particlesSystem := particles.NewParticleSystem(
+			particles.WithArea(utils.NewRect(int(sp.Pos.X), int(sp.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)),
+			particles.WithMotionType(particles.RandomDirections),
+			particles.WithShrinking(0.1),
+			particles.WithModelParticle(*particles.NewParticle(particles.WithColor(sp.color), particles.WithScale(ENEMY_SIZE/4),
+				particles.WithSpeed(1.5))))
+		particlesSystem.Spawn(20)
+		game.particles = append(game.particles, particlesSystem)
+		return true
+	}
+	return false
+}
+func (sp *Splitter) Update() {
+	player := nearestPlayer(sp.Pos)
+
+	sp.Dir = utils.Vec2{X: player.Pos.X - sp.Pos.X, Y: player.Pos.Y - sp.Pos.Y}
+	sp.Dir.NormalizeDir()
+	sp.walk(ENEMY_SIZE)
+
+	if sp.rect().Collide(player.rect()) {
+		sp.Destroyed = true
+		player.TakeDamage(20)
+		return
+	}
+	hitByBullets(sp)
+}
+func (sp *Splitter) TakeDamage(n int) {
+	sp.hp -= n
+	if sp.hp <= 0 && !sp.Destroyed {
+		sp.Destroyed = true
+		game.score++
+		nearestPlayer(sp.Pos).mana += 25
+		DropLoot("splitter", sp.Pos)
+		sp.split()
+	}
+}
+
+// spawns the two smaller bombers on each side of the splitter
+func (sp Splitter) split() {
+	for _, side := range []float32{-1, 1} {
+		b := NewBomber(utils.Vec2{X: sp.Pos.X + side*ENEMY_SIZE/2, Y: sp.Pos.Y})
+		b.size = ENEMY_SIZE / 2
+		b.speed = ENEMY_SPEED * 1.5
+		b.color = SplitterColor
+	}
+}
+func (sp Splitter) Draw(screen *ebiten.Image) {
+	game.cam.FillRect(screen, sp.Pos.X, sp.Pos.Y, ENEMY_SIZE, ENEMY_SIZE, sp.color)
+	//crack in the middle showing where it splits
+	game.cam.StrokeLine(screen, sp.Pos.X+ENEMY_SIZE/2, sp.Pos.Y, sp.Pos.X+ENEMY_SIZE/2, sp.Pos.Y+ENEMY_SIZE, 2, BomberColor)
+}
+
+// Shielder carries a shield that blocks player bullets from the front
+// the shield turns slowly so the player can flank it
+type Shielder struct {
+	DynamicEntity
+	hp     int
+	facing float64 //angle of the shield in radians
+}
+
+func NewShielder(pos utils.Vec2) *Shielder {
+	sh := &Shielder{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, SHIELDER_SPEED, "enemy", ShielderColor, false}, hp: SHIELDER_HP}
+	game.AddEntity(sh)
+	return sh
+}
+func (sh Shielder) rect() utils.Rect {
+	return utils.NewRect(int(sh.Pos.X), int(sh.Pos.Y), ENEMY_SIZE, ENEMY_SIZE)
+}
+func (sh Shielder) Type() string {
+	return sh.etype
+}
+func (sh Shielder) IsDestroyed() bool {
+	if sh.Destroyed {
+		for _, c := range []color.RGBA{sh.color.(color.RGBA), ShieldColor} {
+			particlesSystem := particles.NewParticleSystem(
+				particles.WithArea(utils.NewRect(int(sh.Pos.X-8), int(sh.Pos.Y-8), 16, 16)),
+				particles.WithMotionType(particles.Outward),
+				particles.WithShrinking(0.075),
+				particles.WithModelParticle(*particles.NewParticle(particles.WithColor(c), particles.WithScale(ENEMY_SIZE/3),
+					particles.WithSpeed(1))))
+			particlesSystem.Spawn(8)
+			game.particles = append(game.particles, particlesSystem)
+		}
+		return true
+	}
+	return false
+}
+func (sh *Shielder) Update() {
+	player := nearestPlayer(sh.Pos)
+
+	sh.Dir = utils.Vec2{X: player.Pos.X - sh.Pos.X, Y: player.Pos.Y - sh.Pos.Y}
+	sh.Dir.NormalizeDir()
+	sh.walk(ENEMY_SIZE)
+
+	//turn shield towards the player at limited speed
+	target := math.Atan2(float64(player.Pos.Y-sh.Pos.Y), float64(player.Pos.X-sh.Pos.X))
+	maxTurn := SHIELDER_TURN_RATE / float64(ebiten.TPS())
+	diff := angleDiff(target, sh.facing)
+	sh.facing += math.Max(-maxTurn, math.Min(maxTurn, diff))
+
+	if sh.rect().Collide(player.rect()) {
+		sh.Destroyed = true
+		player.TakeDamage(20)
+	}
+	for _, b := range game.entities["bullet"] {
+		bullet := b.(*Bullet)
+		if bullet.rect().Collide(sh.rect()) && !bullet.IsEnemy() && !bullet.Destroyed {
+			if sh.blocks(bullet) {
+				bullet.destroy()
+				continue
+			}
+			if bullet.hit(sh) {
+				sh.TakeDamage(bullet.damage)
+			}
+		}
+	}
+}
+func (sh *Shielder) TakeDamage(n int) {
+	sh.hp -= n
+	if sh.hp <= 0 && !sh.Destroyed {
+		sh.Destroyed = true
+		game.score += 2
+		nearestPlayer(sh.Pos).mana += 30
+		DropLoot("shielder", sh.Pos)
+	}
+}
+
+// checks if bullet hit the shield side of the shielder
+func (sh Shielder) blocks(b *Bullet) bool {
+	cx, cy := sh.rect().Centre()
+	bx, by := b.rect().Centre()
+	hitAngle := math.Atan2(float64(by-cy), float64(bx-cx))
+	return math.Abs(angleDiff(hitAngle, sh.facing)) <= SHIELD_ARC
+}
+func (sh Shielder) Draw(screen *ebiten.Image) {
+	game.cam.FillRect(screen, sh.Pos.X, sh.Pos.Y, ENEMY_SIZE, ENEMY_SIZE, sh.color)
+	cx, cy := sh.rect().Centre()
+	x, y := float32(cx), float32(cy)
+	r := float32(ENEMY_SIZE) * 0.75
+	//shield arc drawn as short line segments
+	const segments = 6
+	for i := range segments {
+		a1 := sh.facing - SHIELD_ARC + 2*SHIELD_ARC*float64(i)/segments
+		a2 := sh.facing - SHIELD_ARC + 2*SHIELD_ARC*float64(i+1)/segments
+		game.cam.StrokeLine(screen, x+r*float32(math.Cos(a1)), y+r*float32(math.Sin(a1)), x+r*float32(math.Cos(a2)), y+r*float32(math.Sin(a2)), 3, ShieldColor)
+	}
+}
+
+// returns smallest signed difference between two angles in radians
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(a-b+math.Pi, 2*math.Pi)
+	if d < 0 {
+		d += 2 * math.Pi
+	}
+	return d - math.Pi
 }
@@ -17,6 +17,13 @@ type Entity interface {
 	IsDestroyed() bool
 	Type() string
 }
+
+// entities that can be hurt by player bullets and explosions
+type Damageable interface {
+	Entity
+	rect() utils.Rect
+	TakeDamage(n int)
+}
 type DynamicEntity struct {
 	Pos       utils.Vec2
 	Dir       utils.Vec2
@@ -26,7 +33,19 @@ type DynamicEntity struct {
 	Destroyed bool
 }
 
+func (e DynamicEntity) Color() color.Color {
+	return e.color
+}
+
+// moves along Dir at speed slowed by the tile under the centre of an entity of size
+func (e *DynamicEntity) walk(size float32) {
+	speed := e.speed * speedAt(utils.Vec2{X: e.Pos.X + size/2, Y: e.Pos.Y + size/2})
+	e.Pos.X += e.Dir.X * speed
+	e.Pos.Y += e.Dir.Y * speed
+}
+
 func (g *Game) AddEntity(e Entity) {
+	checkSystem(e.Type())
 	g.entities[e.Type()] = append(g.entities[e.Type()], e)
 }
 func (e DynamicEntity) GetNearTiles() map[utils.Vec2]*Tile {
@@ -49,3 +68,13 @@ func (e DynamicEntity) GetNearTiles() map[utils.Vec2]*Tile {
 	}
 	return tiles
 }
+
+// applies damage of player bullets touching e
+func hitByBullets(e Damageable) {
+	for _, b := range game.entities["bullet"] {
+		bullet := b.(*Bullet)
+		if !bullet.IsEnemy() && !bullet.Destroyed && bullet.rect().Collide(e.rect()) && bullet.hit(e) {
+			e.TakeDamage(bullet.damage)
+		}
+	}
+}
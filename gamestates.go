@@ -2,7 +2,7 @@ package main
 
 type State int
 type GameStates struct {
-	Menu, Main, Pause State
+	Menu, Main, Pause, Upgrade, ModeSelect State
 }
 
-var States GameStates = GameStates{0, 1, 2}
+var States GameStates = GameStates{0, 1, 2, 3, 4}
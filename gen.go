@@ -0,0 +1,300 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	START_CLEAR = 2 //tiles around the start kept as air
+	MIN_REGION  = 4 //air pockets smaller than this are filled instead of connected
+
+	STONE_CHANCE   = 0.1  //chance a solid tile is stone
+	CRYSTAL_CHANCE = 0.03 //chance a solid tile is crystal
+)
+
+// Generator fills a grid with rigid (true) or air (false) tiles row by row
+type Generator func(width, height int, rng *rand.Rand, settings GenSettings) []bool
+
+// parameters of procedural maps
+type GenSettings struct {
+	Generator string  //name in generators
+	Density   float32 //0-1 how much of the map starts as rigid
+	Openness  float32 //0-1 fraction of the map the start region must reach
+	Smoothing int     //cellular automata steps for caves
+	Rooms     int     //rooms tried for rooms generator
+	Scale     float32 //noise features size in tiles
+}
+
+var generators = map[string]Generator{
+	"random": randomGen,
+	"caves":  caveGen,
+	"rooms":  roomsGen,
+	"noise":  noiseGen,
+}
+
+var DefaultGenSettings = GenSettings{Generator: "caves", Density: 0.5, Openness: 0.35, Smoothing: 4, Rooms: 8, Scale: 5}
+
+// returns a tilemap made by the generator in settings
+// the region around the top left start is always connected to every big air pocket
+// and covers at least Openness of the map
+func NewGeneratedTilemap(width, height int, settings GenSettings) *Tilemap {
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	gen, ok := generators[settings.Generator]
+	if !ok {
+		gen = randomGen
+	}
+	grid := gen(width, height, rng, settings)
+	ensureConnected(grid, width, height, settings.Openness, rng)
+	t := &Tilemap{Width: width, Height: height, Tiles: make([]*Tile, width*height), Terrain: DefaultTerrain}
+	for i, rigid := range grid {
+		v := Air
+		if rigid {
+			v = solidVariant(rng)
+		}
+		t.Tiles[i] = newTile(i%width, i/width, v)
+	}
+	return t
+}
+
+// most solid tiles are rigid with some stone and crystal mixed in
+func solidVariant(rng *rand.Rand) Variant {
+	switch n := rng.Float32(); {
+	case n < CRYSTAL_CHANCE:
+		return Crystal
+	case n < CRYSTAL_CHANCE+STONE_CHANCE:
+		return Stone
+	}
+	return Rigid
+}
+
+// coin flip for every tile
+func randomGen(width, height int, rng *rand.Rand, s GenSettings) []bool {
+	grid := make([]bool, width*height)
+	for i := range grid {
+		grid[i] = rng.Float32() < s.Density
+	}
+	return grid
+}
+
+// random fill smoothed with cellular automata into round caves
+func caveGen(width, height int, rng *rand.Rand, s GenSettings) []bool {
+	grid := randomGen(width, height, rng, s)
+	for range s.Smoothing {
+		next := make([]bool, len(grid))
+		for i := range grid {
+			walls := rigidNeighbours(grid, width, height, i%width, i/width)
+			next[i] = walls > 4 || (grid[i] && walls == 4)
+		}
+		grid = next
+	}
+	return grid
+}
+
+// counts rigid tiles around x,y . outside the map counts as rigid
+func rigidNeighbours(grid []bool, width, height, x, y int) int {
+	n := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			if nx < 0 || ny < 0 || nx >= width || ny >= height || grid[ny*width+nx] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// rigid map with rectangular rooms joined by corridors . denser maps get smaller rooms
+func roomsGen(width, height int, rng *rand.Rand, s GenSettings) []bool {
+	grid := make([]bool, width*height)
+	for i := range grid {
+		grid[i] = true
+	}
+	maxSize := max(3, int(float32(min(width, height))*(1-s.Density)/2))
+	rooms := []utils.Rect{utils.NewRect(0, 0, START_CLEAR+1, START_CLEAR+1)}
+	for range s.Rooms {
+		w, h := 2+rng.IntN(maxSize-1), 2+rng.IntN(maxSize-1)
+		if w >= width || h >= height {
+			continue
+		}
+		room := utils.NewRect(rng.IntN(width-w), rng.IntN(height-h), w, h)
+		overlaps := false
+		for _, r := range rooms {
+			if room.Collide(r) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			rooms = append(rooms, room)
+		}
+	}
+	for i, r := range rooms {
+		carveRect(grid, width, r)
+		if i > 0 {
+			ax, ay := rooms[i-1].Centre()
+			bx, by := r.Centre()
+			carveTunnel(grid, width, ax, ay, bx, by, rng)
+		}
+	}
+	return grid
+}
+func carveRect(grid []bool, width int, r utils.Rect) {
+	for y := r.Top(); y < r.Bottom(); y++ {
+		for x := r.Left(); x < r.Right(); x++ {
+			grid[y*width+x] = false
+		}
+	}
+}
+
+// carves an L shaped corridor between two tiles
+func carveTunnel(grid []bool, width, ax, ay, bx, by int, rng *rand.Rand) {
+	cornerX, cornerY := bx, ay
+	if rng.IntN(2) == 0 {
+		cornerX, cornerY = ax, by
+	}
+	for _, seg := range [][4]int{{ax, ay, cornerX, cornerY}, {cornerX, cornerY, bx, by}} {
+		x, y := seg[0], seg[1]
+		for {
+			grid[y*width+x] = false
+			if x == seg[2] && y == seg[3] {
+				break
+			}
+			x += sign(seg[2] - x)
+			y += sign(seg[3] - y)
+		}
+	}
+}
+func sign(n int) int {
+	if n > 0 {
+		return 1
+	} else if n < 0 {
+		return -1
+	}
+	return 0
+}
+
+// smooth value noise . tiles where the noise is under Density are rigid
+func noiseGen(width, height int, rng *rand.Rand, s GenSettings) []bool {
+	seed := rng.Uint64()
+	scale := max(s.Scale, 1)
+	grid := make([]bool, width*height)
+	for i := range grid {
+		x, y := float32(i%width), float32(i/width)
+		//two octaves so big shapes have rough edges
+		n := valueNoise(seed, x/scale, y/scale)*0.7 + valueNoise(seed+1, x*2/scale, y*2/scale)*0.3
+		grid[i] = n < s.Density
+	}
+	return grid
+}
+
+// noise between 0 and 1 interpolated between random values on integer points
+func valueNoise(seed uint64, x, y float32) float32 {
+	x0, y0 := float32(math.Floor(float64(x))), float32(math.Floor(float64(y)))
+	fx, fy := smoothstep(x-x0), smoothstep(y-y0)
+	ix, iy := int(x0), int(y0)
+	top := lerp(latticeValue(seed, ix, iy), latticeValue(seed, ix+1, iy), fx)
+	bottom := lerp(latticeValue(seed, ix, iy+1), latticeValue(seed, ix+1, iy+1), fx)
+	return lerp(top, bottom, fy)
+}
+func smoothstep(t float32) float32 {
+	return t * t * (3 - 2*t)
+}
+
+// same value for the same seed and point . splitmix64 hash of the point
+func latticeValue(seed uint64, x, y int) float32 {
+	h := seed ^ uint64(uint32(x))*0x9E3779B97F4A7C15 ^ uint64(uint32(y))*0xC2B2AE3D27D4EB4F
+	h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+	h = (h ^ h>>27) * 0x94D049BB133111EB
+	h ^= h >> 31
+	return float32(h>>40) / (1 << 24)
+}
+
+// clears the start , connects air pockets to the start region and fills tiny ones
+// then digs random tunnels from the start region until it covers openness of the map
+func ensureConnected(grid []bool, width, height int, openness float32, rng *rand.Rand) {
+	carveRect(grid, width, utils.NewRect(0, 0, min(START_CLEAR, width), min(START_CLEAR, height)))
+	regions := airRegions(grid, width, height)
+	start := regions[0]
+	for _, region := range regions[1:] {
+		if len(region) < MIN_REGION {
+			for _, i := range region {
+				grid[i] = true
+			}
+			continue
+		}
+		//connect any tile of the pocket to the start
+		a, b := start[rng.IntN(len(start))], region[rng.IntN(len(region))]
+		carveTunnel(grid, width, a%width, a/width, b%width, b/width, rng)
+	}
+	start = floodFill(grid, width, height, 0)
+	reached := make([]bool, len(grid))
+	for _, i := range start {
+		reached[i] = true
+	}
+	target := int(openness * float32(width*height))
+	for len(start) < target {
+		//random walk from the start region until it reaches more rigid tiles
+		i := start[rng.IntN(len(start))]
+		x, y := i%width, i/width
+		for range width + height {
+			dir := []utils.Point{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}[rng.IntN(4)]
+			x = max(0, min(width-1, x+dir.X))
+			y = max(0, min(height-1, y+dir.Y))
+			if grid[y*width+x] {
+				grid[y*width+x] = false
+				//the walk only crossed air of the region so the dug tile touches it
+				start = append(start, grow(grid, reached, width, height, y*width+x)...)
+				break
+			}
+		}
+	}
+}
+
+// returns air regions with the region of the first tile first
+func airRegions(grid []bool, width, height int) [][]int {
+	seen := make([]bool, len(grid))
+	regions := [][]int{}
+	for i := range grid {
+		if grid[i] || seen[i] {
+			continue
+		}
+		regions = append(regions, grow(grid, seen, width, height, i))
+	}
+	return regions
+}
+
+// returns indices of air tiles reachable from start moving in 4 directions
+func floodFill(grid []bool, width, height, start int) []int {
+	if grid[start] {
+		return nil
+	}
+	return grow(grid, make([]bool, len(grid)), width, height, start)
+}
+
+// marks air tile start and the air tiles reachable from it that arent marked yet as visited
+// returns the newly marked tiles . only the new part is searched so regions can be grown a tile at a time
+func grow(grid []bool, visited []bool, width, height, start int) []int {
+	visited[start] = true
+	queue := []int{start}
+	for n := 0; n < len(queue); n++ {
+		i := queue[n]
+		x, y := i%width, i/width
+		for _, d := range []utils.Point{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}} {
+			nx, ny := x+d.X, y+d.Y
+			j := ny*width + nx
+			if nx < 0 || ny < 0 || nx >= width || ny >= height || grid[j] || visited[j] {
+				continue
+			}
+			visited[j] = true
+			queue = append(queue, j)
+		}
+	}
+	return queue
+}
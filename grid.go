@@ -1,11 +1,11 @@
 package main
 
 import (
-	"image/color"
-	"math/rand"
+	"math"
+	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hasona23/game/level"
 	"github.com/hasona23/game/utils"
 )
 
@@ -13,6 +13,11 @@ const (
 	SPACING   = 1
 	GRID_SIZE = 20.0 //tile
 	TILE_SIZE = 32.0
+
+	CHUNK_SIZE          = 16      //tiles per chunk side
+	CHUNK_LOAD_RADIUS   = 2       //chunks around the player kept loaded
+	CHUNK_UNLOAD_RADIUS = 4       //chunks further than this are unloaded
+	UNBOUNDED           = 1 << 16 //size in tiles used for effectively endless maps
 )
 
 type Variant int
@@ -23,53 +28,196 @@ const (
 )
 
 type Tile struct {
-	Color color.Color
 	Variant
-	X, Y float32
+	X, Y          float32
+	hp            int     //hits left before being dug
+	prev          Variant //variant before the last change
+	changedAt     int     //frame of the last change
+	regrows       bool    //tile was dug and grows back as regrowAs
+	regrowAs      Variant
+	isolated      bool //solid tile with too few neighbours . crumbles isolatedAt + delay
+	isolatedAt    int
+	Sprite        *ebiten.Image //drawn instead of the variant colors when not nil
+	spriteVariant Variant       //variant Sprite was made for . tilemap sprite of the new variant is used after it changes
+}
+
+// tiles of a square part of a chunked tilemap
+type Chunk struct {
+	Tiles    [CHUNK_SIZE * CHUNK_SIZE]*Tile
+	modified bool //a tile changed since it was generated . kept loaded so the change isnt lost
 }
+
+// Tilemap is a grid of Width*Height tiles
+// when chunked tiles are generated in chunks on demand instead of all at once
 type Tilemap struct {
-	Tiles [GRID_SIZE * GRID_SIZE]*Tile
-}
-
-func NewTilemap() (t *Tilemap) {
-	t = &Tilemap{}
-	for i := range t.Tiles {
-		tile := &Tile{}
-		tile.X = float32(i%GRID_SIZE*TILE_SIZE) + float32(i%GRID_SIZE)*float32(SPACING)
-		tile.Y = float32(i/GRID_SIZE*TILE_SIZE) + float32(i/GRID_SIZE)*float32(SPACING)
-
-		if rand.Float32()*100 > 50 && i > 0 {
-			tile.Color = color.Black
-			tile.Variant = Rigid
-		} else {
-			tile.Color = color.White
-			tile.Variant = Air
+	Width, Height  int //in tiles
+	Tiles          []*Tile
+	chunked        bool
+	chunks         map[utils.Point]*Chunk
+	seed           uint64 //chunks generated from the same seed are the same
+	variantSprites map[Variant]*ebiten.Image
+	Terrain        level.Terrain //rules of regrowth and crumbling
+	terrainTick    utils.Timer
+	cache          map[utils.Point]*tileCache //pre rendered tiles of each chunk sized block
+}
+
+// returns a tilemap with all its tiles generated with the default generator
+func NewTilemap(width, height int) *Tilemap {
+	return NewGeneratedTilemap(width, height, DefaultGenSettings)
+}
+
+// returns a tilemap that generates chunks around the player when needed
+// use UNBOUNDED as width and height for a map that never ends
+func NewChunkedTilemap(width, height int) *Tilemap {
+	return &Tilemap{Width: width, Height: height, chunked: true, chunks: make(map[utils.Point]*Chunk), seed: rand.Uint64(),
+		Terrain: DefaultTerrain}
+}
+
+// tile at column x and row y for chunks . chunks cant be flood filled as a whole so its a coin flip
+func (t *Tilemap) generateTile(x, y int, rng *rand.Rand) *Tile {
+	//tiles around the start are kept as air for the players like generated maps
+	if rng.Float32()*100 > 50 && (x >= START_CLEAR || y >= START_CLEAR) {
+		return newTile(x, y, Rigid)
+	}
+	return newTile(x, y, Air)
+}
+func (t *Tilemap) generateChunk(key utils.Point) *Chunk {
+	chunk := &Chunk{}
+	rng := rand.New(rand.NewPCG(t.seed, uint64(uint32(key.X))<<32|uint64(uint32(key.Y))))
+	for i := range chunk.Tiles {
+		chunk.Tiles[i] = t.generateTile(key.X*CHUNK_SIZE+i%CHUNK_SIZE, key.Y*CHUNK_SIZE+i/CHUNK_SIZE, rng)
+	}
+	t.chunks[key] = chunk
+	return chunk
+}
+
+// loads chunks near pos and unloads far ones . does nothing if not chunked
+// modified chunks are never unloaded . unmodified ones are generated the same way again from the seed
+func (t *Tilemap) UpdateChunks(pos utils.Vec2) {
+	if !t.chunked {
+		return
+	}
+	cx := int(pos.X) / (TILE_SIZE + SPACING) / CHUNK_SIZE
+	cy := int(pos.Y) / (TILE_SIZE + SPACING) / CHUNK_SIZE
+	for y := cy - CHUNK_LOAD_RADIUS; y <= cy+CHUNK_LOAD_RADIUS; y++ {
+		for x := cx - CHUNK_LOAD_RADIUS; x <= cx+CHUNK_LOAD_RADIUS; x++ {
+			key := utils.Point{X: x, Y: y}
+			if _, ok := t.chunks[key]; !ok && t.chunkInBounds(key) {
+				t.generateChunk(key)
+			}
+		}
+	}
+	for key, chunk := range t.chunks {
+		if !chunk.modified && max(abs(key.X-cx), abs(key.Y-cy)) > CHUNK_UNLOAD_RADIUS {
+			delete(t.chunks, key)
+			t.dropCache(key)
 		}
+	}
+}
 
-		t.Tiles[i] = tile
+// keeps the chunk of tile loaded from now on
+func (t *Tilemap) markModified(tile *Tile) {
+	if t == nil || !t.chunked {
+		return
+	}
+	key := utils.Point{X: int(tile.X) / TILE_STEP / CHUNK_SIZE, Y: int(tile.Y) / TILE_STEP / CHUNK_SIZE}
+	if chunk, ok := t.chunks[key]; ok {
+		chunk.modified = true
+	}
+}
+func (t *Tilemap) chunkInBounds(key utils.Point) bool {
+	return key.X >= 0 && key.Y >= 0 && key.X*CHUNK_SIZE < t.Width && key.Y*CHUNK_SIZE < t.Height
+}
+func abs(n int) int {
+	if n < 0 {
+		return -n
 	}
-	return t
+	return n
 }
 
-func (t Tilemap) Draw(screen *ebiten.Image) {
-	for _, tile := range t.Tiles {
-		vector.DrawFilledRect(screen, tile.X+game.cam.X, tile.Y+game.cam.Y, TILE_SIZE, TILE_SIZE, tile.Color, false)
+// calls fn for every tile in memory
+func (t *Tilemap) ForEachTile(fn func(tile *Tile)) {
+	if !t.chunked {
+		for _, tile := range t.Tiles {
+			fn(tile)
+		}
+		return
+	}
+	for key, chunk := range t.chunks {
+		for i, tile := range chunk.Tiles {
+			//chunks at the edge of the map can be partly outside it
+			if key.X*CHUNK_SIZE+i%CHUNK_SIZE < t.Width && key.Y*CHUNK_SIZE+i/CHUNK_SIZE < t.Height {
+				fn(tile)
+			}
+		}
+	}
+}
+
+// returns tiles whose centre is within radius of pos
+func (t *Tilemap) TilesInRadius(pos utils.Vec2, radius float32) []*Tile {
+	tiles := []*Tile{}
+	step := float32(TILE_SIZE + SPACING)
+	minX := int(math.Floor(float64((pos.X - radius) / step)))
+	maxX := int(math.Floor(float64((pos.X + radius) / step)))
+	minY := int(math.Floor(float64((pos.Y - radius) / step)))
+	maxY := int(math.Floor(float64((pos.Y + radius) / step)))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			tile := t.TileAt(x, y)
+			if tile == nil {
+				continue
+			}
+			if (utils.Vec2{X: tile.X + TILE_SIZE/2 - pos.X, Y: tile.Y + TILE_SIZE/2 - pos.Y}).Length() <= radius {
+				tiles = append(tiles, tile)
+			}
+		}
+	}
+	return tiles
+}
+
+// returns tiles overlapping r
+func (t *Tilemap) TilesInRect(r utils.Rect) []*Tile {
+	tiles := []*Tile{}
+	step := int(TILE_SIZE + SPACING)
+	for y := r.Y / step; y <= (r.Bottom()-1)/step; y++ {
+		for x := r.X / step; x <= (r.Right()-1)/step; x++ {
+			if tile := t.TileAt(x, y); tile != nil && tile.rect().Collide(r) {
+				tiles = append(tiles, tile)
+			}
+		}
 	}
+	return tiles
 }
+
 func (t *Tilemap) GetWidth() int {
-	return GRID_SIZE*TILE_SIZE + GRID_SIZE*(SPACING)
+	return t.Width*TILE_SIZE + t.Width*(SPACING)
 }
 func (t *Tilemap) GetHieght() int {
-	return GRID_SIZE*TILE_SIZE + GRID_SIZE*(SPACING)
+	return t.Height*TILE_SIZE + t.Height*(SPACING)
 }
-func (t Tilemap) GetTile(pos utils.Vec2) *Tile {
-	// Calculate tile coordinates in world space
-	tileX := int(pos.X) / (TILE_SIZE + SPACING)
-	tileY := int(pos.Y) / (TILE_SIZE + SPACING)
-	index := tileY*GRID_SIZE + tileX
 
-	if index >= len(t.Tiles) || index < 0 {
+// returns tile at column x and row y or nil if outside the map
+// generates the chunk of the tile if its not loaded . far chunks only read are unloaded again by UpdateChunks
+func (t *Tilemap) TileAt(x, y int) *Tile {
+	if x < 0 || y < 0 || x >= t.Width || y >= t.Height {
 		return nil
 	}
-	return t.Tiles[index]
+	if !t.chunked {
+		return t.Tiles[y*t.Width+x]
+	}
+	key := utils.Point{X: x / CHUNK_SIZE, Y: y / CHUNK_SIZE}
+	chunk, ok := t.chunks[key]
+	if !ok {
+		chunk = t.generateChunk(key)
+	}
+	return chunk.Tiles[(y%CHUNK_SIZE)*CHUNK_SIZE+x%CHUNK_SIZE]
+}
+func (t *Tilemap) GetTile(pos utils.Vec2) *Tile {
+	if pos.X < 0 || pos.Y < 0 {
+		return nil
+	}
+	// Calculate tile coordinates in world space
+	tileX := int(pos.X) / (TILE_SIZE + SPACING)
+	tileY := int(pos.Y) / (TILE_SIZE + SPACING)
+	return t.TileAt(tileX, tileY)
 }
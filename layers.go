@@ -0,0 +1,117 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hasona23/game/particles"
+)
+
+// the world is drawn one layer at a time from the back to the front
+type Layer int
+
+const (
+	BackgroundLayer Layer = iota
+	TileLayer
+	DecalLayer //spawn portals and pickups lying on the ground
+	EnemyLayer
+	ProjectileLayer
+	PlayerLayer
+	EffectLayer //particles
+	WorldUILayer
+	ScreenUILayer
+	LAYER_COUNT
+)
+
+var BackgroundColor = color.RGBA{100, 50, 120, 255}
+
+// entities of one type . they update together and draw in layer
+type system struct {
+	etype string
+	layer Layer
+}
+
+// entity types in the order they update each frame . every type must be here
+// each is drawn in its layer in the same order and entities of a type keep the order they were added in
+var systems = []system{
+	{"player", PlayerLayer},
+	{"emitter", ProjectileLayer},
+	{"enemy", EnemyLayer},
+	{"boss", EnemyLayer},
+	{"bullet", ProjectileLayer},
+	{"pickup", DecalLayer},
+}
+
+// particle systems drawn under entities instead of over them
+var decalParticles = []string{"spawn", "bossspawn"}
+
+// entities with bars or labels over them in the world
+type worldUIDrawer interface {
+	DrawWorldUI(screen *ebiten.Image)
+}
+
+// entities of a type missing from systems would never update or draw
+func checkSystem(etype string) {
+	if !slices.ContainsFunc(systems, func(s system) bool { return s.etype == etype }) {
+		log.Fatalf("entity type %v has no system", etype)
+	}
+}
+
+// calls fn for every entity in system order
+func (g *Game) eachEntity(fn func(e Entity)) {
+	for _, s := range systems {
+		for _, e := range g.entities[s.etype] {
+			fn(e)
+		}
+	}
+}
+
+func particleLayer(ps *particles.ParticleSystem) Layer {
+	if slices.Contains(decalParticles, ps.Name) {
+		return DecalLayer
+	}
+	return EffectLayer
+}
+
+// draws the world and hud layer by layer
+func (g *Game) drawLayers(screen *ebiten.Image) {
+	for layer := range LAYER_COUNT {
+		g.drawLayer(screen, layer)
+	}
+}
+func (g *Game) drawLayer(screen *ebiten.Image, layer Layer) {
+	switch layer {
+	case BackgroundLayer:
+		screen.Fill(BackgroundColor)
+	case TileLayer:
+		g.Tilemap.Draw(screen)
+	case WorldUILayer:
+		g.eachEntity(func(e Entity) {
+			if w, ok := e.(worldUIDrawer); ok && !e.IsDestroyed() {
+				w.DrawWorldUI(screen)
+			}
+		})
+	case ScreenUILayer:
+		g.ui[States.Main].Draw(screen)
+		if g.state == States.Upgrade {
+			vector.DrawFilledRect(screen, 0, 0, 320, 240, color.RGBA{0, 0, 0, 180}, false)
+			g.ui[States.Upgrade].Draw(screen)
+		}
+	}
+	for _, ps := range g.particles {
+		if particleLayer(ps) == layer {
+			ps.DrawCam(screen, g.cam)
+		}
+	}
+	for _, s := range systems {
+		if s.layer != layer {
+			continue
+		}
+		for _, e := range g.entities[s.etype] {
+			e.Draw(screen)
+		}
+	}
+}
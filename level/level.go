@@ -0,0 +1,244 @@
+package level
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// current version of the level format . older versions are upgraded by Load
+//
+// version 2 added tilesets , sprites and triggers
+const Version = 2
+
+// names of enemies spawn zones and spawn triggers can use
+// the game adds the names of its spawn table when it starts so both stay the same list
+var Enemies []string
+
+// Level is a hand made map saved as json
+//
+// Tiles are rows of characters and Legend maps each character to a tile variant name
+// all positions and sizes are in tiles not pixels
+type Level struct {
+	Version     int               `json:"version"`
+	Meta        Meta              `json:"meta"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Legend      map[string]string `json:"legend"`
+	Tiles       []string          `json:"tiles"`
+	PlayerStart Point             `json:"player_start"`
+	SpawnZones  []SpawnZone       `json:"spawn_zones"`
+	Objectives  []Objective       `json:"objectives"`
+	Triggers    []Trigger         `json:"triggers,omitempty"`
+	Terrain     *Terrain          `json:"terrain,omitempty"` //nil keeps terrain static
+	//optional sprites used to draw tiles instead of plain colors
+	Tilesets       []Tileset      `json:"tilesets,omitempty"`
+	Sprites        []int          `json:"sprites,omitempty"`         //gid of each tile row by row . 0 means no sprite
+	VariantSprites map[string]int `json:"variant_sprites,omitempty"` //gid drawn when a tile changes to that variant
+}
+
+// image split into tiles . gids from FirstGID to FirstGID+Count-1 belong to it
+type Tileset struct {
+	Image      string `json:"image"`
+	FirstGID   int    `json:"first_gid"`
+	Count      int    `json:"count"`
+	TileWidth  int    `json:"tile_width"`
+	TileHeight int    `json:"tile_height"`
+	Columns    int    `json:"columns"`
+}
+
+// returns the tileset a gid belongs to
+func (l *Level) TilesetOf(gid int) (Tileset, bool) {
+	for _, ts := range l.Tilesets {
+		if gid >= ts.FirstGID && gid < ts.FirstGID+ts.Count {
+			return ts, true
+		}
+	}
+	return Tileset{}, false
+}
+
+// rules of the terrain simulation . zero disables a rule
+type Terrain struct {
+	RegrowDelay       float32 `json:"regrow_delay"`       //seconds before dug tiles grow back
+	CrumbleDelay      float32 `json:"crumble_delay"`      //seconds an isolated tile lasts before crumbling
+	CrumbleNeighbours int     `json:"crumble_neighbours"` //solid tiles with fewer solid neighbours are isolated
+	Transition        float32 `json:"transition"`         //seconds a tile change takes to animate
+}
+
+type Meta struct {
+	Name   string `json:"name"`
+	Author string `json:"author"`
+	Music  string `json:"music"` //path of music file played in the level . can be empty
+}
+
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// area enemies spawn in . if Enemies is empty any enemy can spawn
+type SpawnZone struct {
+	X       int      `json:"x"`
+	Y       int      `json:"y"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Enemies []string `json:"enemies"`
+}
+
+// ObjectiveKinds the game knows how to check
+const (
+	SurviveWaves = "survive_waves" //reach wave Target
+	ReachScore   = "reach_score"   //get Target score
+)
+
+type Objective struct {
+	Kind   string `json:"kind"`
+	Target int    `json:"target"`
+}
+
+// TriggerActions the game knows how to run
+const (
+	ShowMessage = "message" //shows Value as a banner
+	SpawnEnemy  = "spawn"   //spawns enemy named Value in the middle of the trigger
+)
+
+// area that runs an action once when the player enters it
+type Trigger struct {
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Action string `json:"action"`
+	Value  string `json:"value"`
+}
+
+// returns the variant name of the tile at column x and row y
+// rows are indexed by character so legend symbols can be any unicode character
+func (l *Level) VariantAt(x, y int) string {
+	return l.Legend[string([]rune(l.Tiles[y])[x])]
+}
+
+// checks the level is complete and consistent . older versions must be upgraded first
+func (l *Level) Validate() error {
+	if l.Version != Version {
+		return fmt.Errorf("level version %v isnt the current version %v", l.Version, Version)
+	}
+	if l.Width < 1 || l.Height < 1 {
+		return fmt.Errorf("level size must be positive got %vx%v", l.Width, l.Height)
+	}
+	if len(l.Tiles) != l.Height {
+		return fmt.Errorf("level has %v rows but height is %v", len(l.Tiles), l.Height)
+	}
+	for y, row := range l.Tiles {
+		runes := []rune(row)
+		if len(runes) != l.Width {
+			return fmt.Errorf("row %v has %v tiles but width is %v", y, len(runes), l.Width)
+		}
+		for x, c := range runes {
+			if _, ok := l.Legend[string(c)]; !ok {
+				return fmt.Errorf("tile %q at %v,%v is not in the legend", c, x, y)
+			}
+		}
+	}
+	if !l.inBounds(l.PlayerStart.X, l.PlayerStart.Y) {
+		return fmt.Errorf("player start %v,%v is outside the level", l.PlayerStart.X, l.PlayerStart.Y)
+	}
+	for i, z := range l.SpawnZones {
+		if z.Width < 1 || z.Height < 1 || !l.inBounds(z.X, z.Y) || !l.inBounds(z.X+z.Width-1, z.Y+z.Height-1) {
+			return fmt.Errorf("spawn zone %v is outside the level", i)
+		}
+		for _, name := range z.Enemies {
+			if !slices.Contains(Enemies, name) {
+				return fmt.Errorf("spawn zone %v has unknown enemy %q . known enemies are %v", i, name, Enemies)
+			}
+		}
+	}
+	for i, o := range l.Objectives {
+		switch o.Kind {
+		case SurviveWaves, ReachScore:
+		default:
+			return fmt.Errorf("objective %v has unknown kind %q", i, o.Kind)
+		}
+	}
+	for i, t := range l.Triggers {
+		if t.Width < 1 || t.Height < 1 || !l.inBounds(t.X, t.Y) || !l.inBounds(t.X+t.Width-1, t.Y+t.Height-1) {
+			return fmt.Errorf("trigger %v is outside the level", i)
+		}
+		switch t.Action {
+		case ShowMessage:
+		case SpawnEnemy:
+			if !slices.Contains(Enemies, t.Value) {
+				return fmt.Errorf("trigger %v spawns unknown enemy %q . known enemies are %v", i, t.Value, Enemies)
+			}
+		default:
+			return fmt.Errorf("trigger %v has unknown action %q", i, t.Action)
+		}
+	}
+	for i, ts := range l.Tilesets {
+		if ts.TileWidth < 1 || ts.TileHeight < 1 || ts.Columns < 1 || ts.Count < 1 || ts.FirstGID < 1 {
+			return fmt.Errorf("tileset %v has invalid size , columns , count or first gid", i)
+		}
+	}
+	if t := l.Terrain; t != nil && (t.RegrowDelay < 0 || t.CrumbleDelay < 0 || t.Transition < 0 || t.CrumbleNeighbours < 0 || t.CrumbleNeighbours > 8) {
+		return fmt.Errorf("terrain rules cant be negative and crumble neighbours is at most 8")
+	}
+	if len(l.Sprites) > 0 && len(l.Sprites) != l.Width*l.Height {
+		return fmt.Errorf("level has %v sprites but %v tiles", len(l.Sprites), l.Width*l.Height)
+	}
+	for i, gid := range l.Sprites {
+		if _, ok := l.TilesetOf(gid); gid != 0 && !ok {
+			return fmt.Errorf("sprite %v at %v,%v is in no tileset", gid, i%l.Width, i/l.Width)
+		}
+	}
+	for name, gid := range l.VariantSprites {
+		if _, ok := l.TilesetOf(gid); !ok {
+			return fmt.Errorf("sprite %v of variant %q is in no tileset", gid, name)
+		}
+	}
+	return nil
+}
+func (l *Level) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < l.Width && y < l.Height
+}
+
+// reads and validates a level file
+func Load(path string) (*Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &Level{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("level %v: %w", path, err)
+	}
+	if l.Version < 1 || l.Version > Version {
+		return nil, fmt.Errorf("level %v: unsupported level version %v", path, l.Version)
+	}
+	l.upgrade()
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("level %v: %w", path, err)
+	}
+	return l, nil
+}
+
+// brings a level of an older version up to Version one version at a time
+func (l *Level) upgrade() {
+	if l.Version == 1 {
+		//version 1 had no tilesets , sprites or triggers . they load empty so only the version changes
+		l.Version = 2
+	}
+}
+
+// writes the level to path as the current version
+func Save(path string, l *Level) error {
+	l.Version = Version
+	if err := l.Validate(); err != nil {
+		return fmt.Errorf("level %v: %w", path, err)
+	}
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hasona23/game/level"
+	"github.com/hasona23/game/tiled"
+	"github.com/hasona23/game/utils"
+)
+
+const SAVED_LEVEL = "./levels/saved.json"
+
+// builds a tilemap from the tiles of a level
+func NewTilemapFromLevel(l *level.Level) (*Tilemap, error) {
+	t := &Tilemap{Width: l.Width, Height: l.Height, Tiles: make([]*Tile, l.Width*l.Height)}
+	for y := range l.Height {
+		for x := range l.Width {
+			v, ok := variantNames[l.VariantAt(x, y)]
+			if !ok {
+				return nil, fmt.Errorf("unknown tile variant %q at %v,%v", l.VariantAt(x, y), x, y)
+			}
+			t.Tiles[y*l.Width+x] = newTile(x, y, v)
+		}
+	}
+	if l.Terrain != nil {
+		t.Terrain = *l.Terrain
+	}
+	if err := t.loadSprites(l); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loads the tileset images of the level and gives tiles their sprites
+func (t *Tilemap) loadSprites(l *level.Level) error {
+	if len(l.Tilesets) == 0 {
+		return nil
+	}
+	images := map[string]*ebiten.Image{}
+	for _, ts := range l.Tilesets {
+		img, _, err := ebitenutil.NewImageFromFile(ts.Image)
+		if err != nil {
+			return fmt.Errorf("tileset %v: %w", ts.Image, err)
+		}
+		images[ts.Image] = img
+	}
+	sprite := func(gid int) *ebiten.Image {
+		ts, ok := l.TilesetOf(gid)
+		if !ok {
+			return nil
+		}
+		i := gid - ts.FirstGID
+		x, y := i%ts.Columns*ts.TileWidth, i/ts.Columns*ts.TileHeight
+		return images[ts.Image].SubImage(image.Rect(x, y, x+ts.TileWidth, y+ts.TileHeight)).(*ebiten.Image)
+	}
+	for i, gid := range l.Sprites {
+		t.Tiles[i].Sprite = sprite(gid)
+	}
+	t.variantSprites = make(map[Variant]*ebiten.Image)
+	for name, gid := range l.VariantSprites {
+		if v, ok := variantNames[name]; ok {
+			t.variantSprites[v] = sprite(gid)
+		}
+	}
+	return nil
+}
+
+// turns the tilemap into a level so it can be saved
+// chunked maps cant be saved as they have no fixed tiles
+func (t *Tilemap) ToLevel(meta level.Meta, start level.Point) (*level.Level, error) {
+	if t.chunked {
+		return nil, fmt.Errorf("chunked tilemaps cant be saved as levels")
+	}
+	l := &level.Level{Version: level.Version, Meta: meta, Width: t.Width, Height: t.Height,
+		Legend: map[string]string{}, PlayerStart: start}
+	for _, tt := range tileTypes {
+		l.Legend[tt.Symbol] = tt.Name
+	}
+	for y := range t.Height {
+		row := ""
+		for x := range t.Width {
+			row += t.TileAt(x, y).Props().Symbol
+		}
+		l.Tiles = append(l.Tiles, row)
+	}
+	return l, nil
+}
+
+// position in pixels of the top left of tile x,y
+func tilePos(x, y int) utils.Vec2 {
+	return utils.Vec2{X: float32(x) * (TILE_SIZE + SPACING), Y: float32(y) * (TILE_SIZE + SPACING)}
+}
+
+// replaces the map with the level at path and moves the player to its start
+// tiled maps (.tmx , .tmj and .json exported by Tiled) are imported with the tiled package
+func (g *Game) loadLevel(path string) error {
+	load := level.Load
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tmx", ".tmj":
+		load = tiled.Load
+	case ".json":
+		if tiled.IsJSONMap(path) {
+			load = tiled.Load
+		}
+	}
+	l, err := load(path)
+	if err != nil {
+		return err
+	}
+	t, err := NewTilemapFromLevel(l)
+	if err != nil {
+		return fmt.Errorf("level %v: %w", path, err)
+	}
+	g.level = l
+	g.levelPath = path
+	g.Tilemap = t
+	g.triggered = make([]bool, len(l.Triggers))
+	for _, p := range g.players() {
+		p.Pos = tilePos(l.PlayerStart.X, l.PlayerStart.Y)
+		p.Pos.X += float32(p.index * PLAYER_RECT_SIZE)
+	}
+	centre := g.playersCentre()
+	g.cam.GoTo(centre.X, centre.Y)
+	if err := playMusic(l.Meta.Music); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+// saves current map so it can be edited and shipped as a level
+func (g *Game) saveLevel(path string) error {
+	player := g.players()[0]
+	start := level.Point{X: int(player.Pos.X) / (TILE_SIZE + SPACING), Y: int(player.Pos.Y) / (TILE_SIZE + SPACING)}
+	meta := level.Meta{Name: "Untitled"}
+	if g.level != nil {
+		meta = g.level.Meta
+	}
+	l, err := g.Tilemap.ToLevel(meta, start)
+	if err != nil {
+		return err
+	}
+	if g.level != nil {
+		l.SpawnZones = g.level.SpawnZones
+		l.Objectives = g.level.Objectives
+		l.Triggers = g.level.Triggers
+		l.Terrain = g.level.Terrain
+		l.Tilesets = g.level.Tilesets
+		l.VariantSprites = g.level.VariantSprites
+		//sprites belong to the tiles as loaded . once tiles changed they would draw the wrong tile
+		if sameTiles(l, g.level) {
+			l.Sprites = g.level.Sprites
+		}
+	}
+	return level.Save(path, l)
+}
+
+// true when both levels have the same variant at every tile
+func sameTiles(a, b *level.Level) bool {
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	}
+	for y := range a.Height {
+		for x := range a.Width {
+			if a.VariantAt(x, y) != b.VariantAt(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// picks a random spawn zone of the level and a random point inside it
+// returns false if level has no spawn zones
+func (g *Game) levelSpawn() (pos utils.Vec2, enemies []string, ok bool) {
+	if g.level == nil || len(g.level.SpawnZones) == 0 {
+		return utils.Vec2{}, nil, false
+	}
+	z := g.level.SpawnZones[rand.IntN(len(g.level.SpawnZones))]
+	pos = tilePos(z.X+rand.IntN(z.Width), z.Y+rand.IntN(z.Height))
+	return pos, z.Enemies, true
+}
+
+// checks if all objectives of the level are done
+func (g *Game) objectivesComplete() bool {
+	if g.level == nil || len(g.level.Objectives) == 0 {
+		return false
+	}
+	return !slices.ContainsFunc(g.level.Objectives, func(o level.Objective) bool {
+		switch o.Kind {
+		case level.SurviveWaves:
+			return g.wave < o.Target
+		case level.ReachScore:
+			return g.score < o.Target
+		}
+		return true
+	})
+}
+
+// runs the action of each trigger the first time any living player enters it
+func (g *Game) updateTriggers() {
+	if g.level == nil {
+		return
+	}
+	players := g.livingPlayers()
+	for i, t := range g.level.Triggers {
+		if g.triggered[i] {
+			continue
+		}
+		pos := tilePos(t.X, t.Y)
+		area := utils.NewRect(int(pos.X), int(pos.Y), t.Width*(TILE_SIZE+SPACING), t.Height*(TILE_SIZE+SPACING))
+		if !slices.ContainsFunc(players, func(p *Player) bool { return area.Collide(p.rect()) }) {
+			continue
+		}
+		g.triggered[i] = true
+		switch t.Action {
+		case level.ShowMessage:
+			g.showBanner(t.Value, 2)
+		case level.SpawnEnemy:
+			x, y := area.Centre()
+			SpawnEnemy(utils.Vec2{X: float32(x), Y: float32(y)}, []string{t.Value})
+		}
+	}
+}
@@ -1,32 +1,66 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
 	"log"
-	"math/rand/v2"
 	"os"
 	"slices"
+	"strconv"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hasona23/game/level"
+	"github.com/hasona23/game/netplay"
 	"github.com/hasona23/game/particles"
+	"github.com/hasona23/game/patterns"
 	"github.com/hasona23/game/ui"
 	"github.com/hasona23/game/utils"
 )
 
 const (
 	SPAWN_TIME = 3
+
+	CAM_LOOK_AHEAD = 0.2 //share of the way to the aim point the camera leans
+	BOSS_ZOOM      = 0.8 //camera zooms out during boss fights so the slams are in view
 )
 
+var CamDeadzone = utils.Vec2{X: 16, Y: 12}
+
 type Game struct {
-	cam          utils.Cam
-	Tilemap      *Tilemap
-	entities     map[string][]Entity
-	particles    []*particles.ParticleSystem
-	enemySpawner utils.Timer
-	score        int
-	state        State
-	ui           map[State]*ui.UILayout
+	cam              utils.Cam
+	Tilemap          *Tilemap
+	entities         map[string][]Entity
+	particles        []*particles.ParticleSystem
+	enemySpawner     utils.Timer
+	wave             int
+	waveSpawns       int //enemies spawned in current wave
+	bossFight        bool
+	slowed           bool //enemies update every other frame while slowed
+	slow             utils.Timer
+	frame            int
+	banner           utils.Timer //how long the banner label stays on screen
+	score            int
+	difficulty       Difficulty
+	oneHitKills      bool //hardcore . any damage that gets through the shield kills
+	state            State
+	ui               map[State]*ui.UILayout
+	font             []byte
+	level            *level.Level //nil when map is procedural
+	levelPath        string
+	levelDone        bool
+	triggered        []bool                                 //triggers of the level that already ran
+	portals          map[*particles.ParticleSystem][]string //enemies allowed to come out of each spawn portal
+	mapGen           GenSettings                            //how procedural maps are generated
+	coop             bool                                   //second local player
+	chunkedMap       bool                                   //endless map generated in chunks instead of a fixed grid
+	mode             Mode                                   //rules of the current run
+	newMode          func() Mode                            //makes a fresh mode when restarting
+	bestScores       map[string]int                         //best score of each mode this session
+	modeDescriptions map[string]string                      //shown on mode select for the focused button
+	server           *netServer                             //set when running as a headless server
+	client           *netClient                             //set when playing on a server . the game only shows its snapshots
 }
 
 func (g *Game) Init() {
@@ -34,36 +68,127 @@ func (g *Game) Init() {
 	if err != nil {
 		log.Fatal("Error opening font file: err")
 	}
+	bulletPatterns, err = patterns.Load("./patterns.json")
+	if err != nil {
+		log.Fatal("Error loading bullet patterns: ", err)
+	}
+	upgrades, err = LoadUpgrades("./upgrades.json")
+	if err != nil {
+		log.Fatal("Error loading upgrades: ", err)
+	}
+	if err := LoadAutotiles(); err != nil {
+		log.Fatal("Error loading autotiles: ", err)
+	}
+	g.font = font
 	g.cam = *utils.NewCamera(0, 0)
-	g.Tilemap = NewTilemap()
+	g.cam.Deadzone = CamDeadzone
+	g.cam.LookAhead = CAM_LOOK_AHEAD
+	if g.mapGen.Generator == "" {
+		g.mapGen = DefaultGenSettings
+	}
+	if g.chunkedMap {
+		g.Tilemap = NewChunkedTilemap(UNBOUNDED, UNBOUNDED)
+	} else {
+		g.Tilemap = NewGeneratedTilemap(GRID_SIZE, GRID_SIZE, g.mapGen)
+	}
 	g.entities = make(map[string][]Entity)
-	g.AddEntity(NewPlayer(5, 5))
+	//online players are added by the server when they join
+	if g.server == nil {
+		g.AddEntity(NewPlayer(5, 5))
+		if g.coop {
+			g.AddEntity(NewSecondPlayer(5+PLAYER_RECT_SIZE, 5))
+		}
+	}
+	g.level = nil
+	g.levelDone = false
+	g.triggered = nil
+	if g.mode == nil {
+		g.mode, g.newMode = NewEndless(), NewEndless
+	}
+	if g.bestScores == nil {
+		g.bestScores = make(map[string]int)
+	}
+	g.portals = make(map[*particles.ParticleSystem][]string)
+	if g.levelPath != "" {
+		if err := g.loadLevel(g.levelPath); err != nil {
+			log.Println(err)
+			g.levelPath = ""
+		}
+	}
+	centre := g.playersCentre()
+	g.cam.GoTo(centre.X, centre.Y)
 	g.enemySpawner = utils.NewTimer(SPAWN_TIME)
 	g.state = States.Menu
 	g.score = 0
+	g.wave = 1
+	g.waveSpawns = 0
+	g.bossFight = false
+	g.slowed = false
+	g.oneHitKills = false
 	//UI===============================
 	//main menu
 	g.ui = make(map[State]*ui.UILayout)
 	menuLayout := ui.NewUILayout("menu")
-	startbtn := ui.NewButton("Start", 120, 120, 16, 2, font, color.White, color.Black, color.Black)
-	startbtn.AddClickEvent(func(b *ui.Button) { g.state = States.Main })
-	exitbtn := ui.NewButton("Exit", 120, 160, 16, 2, font, color.White, color.Black, color.Black)
+	startbtn := ui.NewButton("Start", 120, 60, 16, 2, font, color.White, color.Black, color.Black)
+	startbtn.AddClickEvent(func(b *ui.Button) { g.state = States.ModeSelect })
+	arenabtn := ui.NewButton("Arena", 120, 95, 16, 2, font, color.White, color.Black, color.Black)
+	arenabtn.AddClickEvent(func(b *ui.Button) {
+		if err := g.loadLevel("./levels/arena.json"); err != nil {
+			log.Println(err)
+			return
+		}
+		g.startMode(NewEndless)
+	})
+	cavebtn := ui.NewButton("Cave", 120, 130, 16, 2, font, color.White, color.Black, color.Black)
+	cavebtn.AddClickEvent(func(b *ui.Button) {
+		if err := g.loadLevel("./levels/cave.tmx"); err != nil {
+			log.Println(err)
+			return
+		}
+		g.startMode(NewEndless)
+	})
+	exitbtn := ui.NewButton("Exit", 120, 165, 16, 2, font, color.White, color.Black, color.Black)
 	exitbtn.AddClickEvent(func(b *ui.Button) { os.Exit(0) })
 	menuLayout.AddButton("startbtn", startbtn)
+	menuLayout.AddButton("arenabtn", arenabtn)
+	menuLayout.AddButton("cavebtn", cavebtn)
 	menuLayout.AddButton("exitbtn", exitbtn)
 	menuLayout.ApplyHoverToAllButtons(onhover)
 	g.ui[States.Menu] = menuLayout
+	g.ui[States.ModeSelect] = g.modeSelectLayout()
 	//game ui
 	mainLayout := ui.NewUILayout("main")
-	hpBar := ui.NewBar(5, 5, g.entities["player"][0].(*Player).hp, 8, utils.Point{X: 1, Y: 1}, color.RGBA{255, 0, 100, 255}, color.Gray{123})
-	mainLayout.AddBar("hp", hpBar)
+	for _, p := range g.players() {
+		addPlayerHUD(mainLayout, p, font)
+	}
 	score := ui.NewLabel(fmt.Sprintf("Score:%v", g.score), 5, 24, font, 16, color.RGBA{0, 100, 255, 255})
 	mainLayout.AddLabel("score", score)
-	manaBar := ui.NewBar(5, 16, g.entities["player"][0].(*Player).mana, 8, utils.Point{X: 1, Y: 1}, color.RGBA{100, 0, 255, 255}, color.Gray{123})
-	mainLayout.AddBar("mana", manaBar)
+	wave := ui.NewLabel(fmt.Sprintf("wave: %v", g.wave), 250, 5, font, 16, color.RGBA{0, 100, 255, 255})
+	mainLayout.AddLabel("wave", wave)
+	banner := ui.NewLabel("", 160, 100, font, 24, color.RGBA{255, 215, 0, 255})
+	mainLayout.AddLabel("banner", banner)
 	g.ui[States.Main] = mainLayout
 
 }
+
+// shows a big centered message for some seconds
+func (g *Game) showBanner(txt string, seconds float32) {
+	label, _ := g.ui[States.Main].GetLabel("banner")
+	label.SetText(txt)
+	label.SetPosition(160, 100)
+	label.CenterText()
+	g.banner = utils.NewTimer(seconds)
+}
+func (g *Game) updateBanner() {
+	label, _ := g.ui[States.Main].GetLabel("banner")
+	if label.GetText() == "" {
+		return
+	}
+	g.banner.UpdateTimer()
+	if g.banner.Ticked() {
+		label.SetText("")
+	}
+}
 func onhover(b *ui.Button) {
 	b.Style.BorderColor = color.White
 }
@@ -72,86 +197,122 @@ func (g *Game) Update() error {
 	switch g.state {
 	case States.Menu:
 		g.ui[States.Menu].Update()
+	case States.Upgrade:
+		g.ui[States.Upgrade].Update()
+	case States.ModeSelect:
+		g.updateModeSelect()
 	case States.Main:
-		player := g.entities["player"][0].(*Player)
 		g.ui[States.Main].Update()
-		bar, _ := g.ui[States.Main].GetBar("hp")
-		bar.SetValue(player.hp)
-		mbar, _ := g.ui[States.Main].GetBar("mana")
-		mbar.SetValue(player.mana)
+		if g.client != nil {
+			g.updateClient()
+			return nil
+		}
+		for _, p := range g.players() {
+			updatePlayerHUD(g.ui[States.Main], p)
+		}
 		label, _ := g.ui[States.Main].GetLabel("score")
 		label.SetText(fmt.Sprintf("score: %v", g.score))
-		g.cam.FollowTarget(player.Pos.X, player.Pos.Y, 320, 240, 2)
-		g.cam.Constrain(g.Tilemap.GetWidth(), g.Tilemap.GetHieght(), 320, 240)
-		g.enemySpawner.UpdateTimer()
-		if g.enemySpawner.Ticked() {
-			x := rand.Float32() * GRID_SIZE * TILE_SIZE
-			y := rand.Float32() * GRID_SIZE * TILE_SIZE
-			particlesSystem := particles.NewParticleSystem(
-				particles.WithArea(utils.NewRect(int(x), int(y), 32, 32)),
-				particles.WithName("spawn"),
-				particles.WithMotionType(particles.Circular),
-				particles.WithShrinking(0.2),
-				particles.WithModelParticle(*particles.NewParticle(particles.WithColor(color.RGBA{255, 0, 0, 255}), particles.WithScale(16),
-					particles.WithSpeed(0.5))))
-			particlesSystem.Spawn(10)
-
-			g.particles = append(g.particles, particlesSystem)
-		}
-		for _, entities := range g.entities {
-			for i := range entities {
-				if !entities[i].IsDestroyed() {
-					entities[i].Update()
-				}
+		g.updateCamera()
+		wlabel, _ := g.ui[States.Main].GetLabel("wave")
+		wlabel.SetText(fmt.Sprintf("wave: %v", g.wave))
+		if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+			if err := g.saveLevel(SAVED_LEVEL); err != nil {
+				log.Println(err)
+			} else {
+				g.showBanner("LEVEL SAVED", 1)
 			}
 		}
-		for k := range g.entities {
-			g.entities[k] = slices.DeleteFunc(g.entities[k], func(e Entity) bool { return e.IsDestroyed() })
-		}
+		g.updateWorld()
+	}
+	return nil
+}
 
-		g.particles = slices.DeleteFunc(g.particles, func(ps *particles.ParticleSystem) bool {
-			if len(ps.Particles) == 0 && ps.Name == "spawn" {
-				x, y := ps.Area.Centre()
-				n := rand.Float32() * 100
-				if n > 25 {
-					NewBomber(utils.Vec2{X: float32(x), Y: float32(y)})
-				} else {
-					NewSniper(utils.Vec2{X: float32(x), Y: float32(y)})
-				}
-
-				return true
-			}
-			return false
-		})
-		for i := range g.particles {
-			g.particles[i].Update()
+// follows the players looking ahead to where a lone player aims
+func (g *Game) updateCamera() {
+	centre := g.playersCentre()
+	aim := centre
+	if players := g.livingPlayers(); len(players) == 1 {
+		aim = players[0].aimPoint()
+	}
+	g.cam.Follow(centre, aim)
+	g.cam.Constrain(g.Tilemap.GetWidth(), g.Tilemap.GetHieght())
+	if g.bossFight {
+		g.cam.SetZoom(BOSS_ZOOM)
+	} else {
+		g.cam.SetZoom(1)
+	}
+	g.cam.Update()
+}
+
+// one tick of the simulation . needs no window so the server runs it headless
+func (g *Game) updateWorld() {
+	g.Tilemap.UpdateChunks(g.playersCentre())
+	g.Tilemap.UpdateTerrain()
+	g.updateBanner()
+	g.updateTriggers()
+	if !g.updateMode() {
+		return
+	}
+	g.enemySpawner.UpdateTimer()
+	if !g.levelDone && g.objectivesComplete() {
+		g.levelDone = true
+		g.showBanner("LEVEL COMPLETE", 3)
+		playFanfare()
+	}
+	if g.enemySpawner.Ticked() && !g.bossFight && !g.levelDone {
+		g.spawnNext()
+	}
+	g.frame++
+	if g.slowed {
+		g.slow.UpdateTimer()
+		if g.slow.Ticked() {
+			g.slowed = false
 		}
-		if len(g.entities["player"]) == 0 {
-			game.Init()
-			game.state = States.Main
+	}
+	//entities added while updating wait for the next frame
+	for _, s := range systems {
+		entities := g.entities[s.etype]
+		for i := range entities {
+			if !entities[i].IsDestroyed() && !isSlowed(entities[i]) {
+				entities[i].Update()
+			}
 		}
+	}
+	for k := range g.entities {
+		g.entities[k] = slices.DeleteFunc(g.entities[k], func(e Entity) bool { return e.IsDestroyed() })
+	}
 
+	g.particles = slices.DeleteFunc(g.particles, func(ps *particles.ParticleSystem) bool {
+		if len(ps.Particles) == 0 && ps.Name == "spawn" {
+			x, y := ps.Area.Centre()
+			SpawnEnemy(utils.Vec2{X: float32(x), Y: float32(y)}, g.portals[ps])
+			delete(g.portals, ps)
+
+			return true
+		}
+		if len(ps.Particles) == 0 && ps.Name == "bossspawn" {
+			x, y := ps.Area.Centre()
+			NewBoss(utils.Vec2{X: float32(x) - BOSS_SIZE/2, Y: float32(y) - BOSS_SIZE/2})
+			return true
+		}
+		//finished effects like trails and explosions
+		return len(ps.Particles) == 0 && !ps.IsLooped
+	})
+	for i := range g.particles {
+		g.particles[i].Update()
+	}
+	if len(g.entities["player"]) == 0 || g.allPlayersDown() {
+		g.restartMode()
 	}
-	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	switch {
-	case g.state == States.Menu:
+	case g.state == States.Menu || g.state == States.ModeSelect:
 		screen.Fill(color.Black)
-		g.ui[States.Menu].Draw(screen)
-	case g.state == States.Main || g.state == States.Pause:
-		screen.Fill(color.RGBA{100, 50, 120, 255})
-		g.Tilemap.Draw(screen)
-		for _, ps := range g.particles {
-			ps.DrawCam(screen, g.cam)
-		}
-		for _, entities := range g.entities {
-			for i := range entities {
-				entities[i].Draw(screen)
-			}
-		}
-		g.ui[States.Main].Draw(screen)
+		g.ui[g.state].Draw(screen)
+	case g.state == States.Main || g.state == States.Pause || g.state == States.Upgrade:
+		g.drawLayers(screen)
 	case g.state == States.Pause:
 
 	}
@@ -163,11 +324,60 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 
 var game Game
 
+// sets a float32 setting from a flag
+func floatFlag(f *float32) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseFloat(s, 32)
+		*f = float32(v)
+		return err
+	}
+}
+
 func main() {
 	ebiten.SetWindowSize(640, 480)
 	ebiten.SetWindowTitle("Survive")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	serve := flag.String("server", "", "run a headless server on this address like :7777")
+	connect := flag.String("connect", "", "join the server at this address like localhost:7777")
+	latency := flag.Duration("latency", 0, "delay added to sent packets to try a bad network")
+	loss := flag.Float64("loss", 0, "chance from 0 to 1 that a sent packet is dropped")
+	game.difficulty = Normal
+	flag.Func("difficulty", "easy , normal or hard", func(s string) (err error) {
+		game.difficulty, err = ParseDifficulty(s)
+		return err
+	})
+	flag.BoolVar(&game.chunkedMap, "chunked", false, "play on an endless map generated in chunks")
+	game.mapGen = DefaultGenSettings
+	flag.StringVar(&game.mapGen.Generator, "gen", game.mapGen.Generator, "map generator : caves , rooms , noise or random")
+	flag.Func("density", "0-1 how much of a generated map starts as rigid", floatFlag(&game.mapGen.Density))
+	flag.Func("openness", "0-1 how much of a generated map the start must reach", floatFlag(&game.mapGen.Openness))
+	flag.IntVar(&game.mapGen.Smoothing, "smoothing", game.mapGen.Smoothing, "cellular automata steps of caves")
+	flag.IntVar(&game.mapGen.Rooms, "rooms", game.mapGen.Rooms, "rooms tried by the rooms generator")
+	flag.Func("scale", "size in tiles of noise features", floatFlag(&game.mapGen.Scale))
+	flag.Parse()
+	if _, ok := generators[game.mapGen.Generator]; !ok {
+		log.Fatalf("unknown generator %q", game.mapGen.Generator)
+	}
+	if s := game.mapGen; s.Density < 0 || s.Density > 1 || s.Openness < 0 || s.Openness > 1 {
+		log.Fatal("density and openness must be between 0 and 1")
+	}
+	//level to play can be passed as first argument
+	if flag.NArg() > 0 {
+		game.levelPath = flag.Arg(0)
+	}
+	conditions := netplay.Conditions{Latency: *latency, Jitter: *latency / 4, Loss: *loss}
+	if *serve != "" {
+		if err := runServer(*serve, conditions); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	game.Init()
+	if *connect != "" {
+		if err := game.connect(*connect, conditions); err != nil {
+			log.Fatal(err)
+		}
+	}
 	if err := ebiten.RunGame(&game); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hasona23/game/ui"
+	"github.com/hasona23/game/utils"
+)
+
+// result of a mode each frame
+type ModeResult int
+
+const (
+	Playing ModeResult = iota
+	Won
+	Lost
+)
+
+// which enemies come and how often
+type SpawnConfig struct {
+	Interval  float32  //seconds between spawns
+	Enemies   []string //names from spawnTable . empty allows all
+	BossWaves bool     //boss every BOSS_WAVE waves
+}
+
+// Mode supplies the rules of a run
+//
+// Start is called on a fresh game to change rules and add to the hud
+// Update checks win and lose every frame . it stops being called after the run is won
+type Mode interface {
+	Name() string
+	Description() string
+	Spawns() SpawnConfig
+	Start(g *Game)
+	Update(g *Game) ModeResult
+}
+
+// modes in the order shown on the mode select screen
+var modes = []func() Mode{NewEndless, NewTimeAttack, NewSurvivalWaves, NewHardcore, NewTerritory}
+
+// starts a fresh run of the mode newMode makes . it makes a new one for restarts after dying
+// so timers and progress of the last run dont carry over
+func (g *Game) startMode(newMode func() Mode) {
+	m := newMode()
+	g.mode, g.newMode = m, newMode
+	g.enemySpawner = utils.NewTimer(m.Spawns().Interval)
+	m.Start(g)
+	g.state = States.Main
+}
+
+// restarts the current mode after the player died
+func (g *Game) restartMode() {
+	newMode := g.newMode
+	g.Init()
+	g.startMode(newMode)
+}
+
+// runs the mode rules . returns false if the run ended and the game was reset
+// a won or lost run keeps going without spawns until its banner is gone or Esc is pressed so the player sees why it ended
+func (g *Game) updateMode() bool {
+	if g.levelDone {
+		label, _ := g.ui[States.Main].GetLabel("banner")
+		if label.GetText() == "" || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			g.endRun()
+			return false
+		}
+		return true
+	}
+	switch g.mode.Update(g) {
+	case Won:
+		g.levelDone = true
+		playFanfare()
+	case Lost:
+		g.levelDone = true
+	}
+	return true
+}
+
+// leaves a finished run for the mode select screen keeping the mode and choices made there
+// the server has nobody to pick so it plays the mode again
+func (g *Game) endRun() {
+	if g.server != nil {
+		g.restartMode()
+		return
+	}
+	g.Init()
+	g.state = States.ModeSelect
+}
+
+// screen after Start with a button for each mode and the description of the focused one
+func (g *Game) modeSelectLayout() *ui.UILayout {
+	layout := ui.NewUILayout("modes")
+	layout.AddLabel("title", ui.NewLabel("Select mode", 100, 10, g.font, 16, color.White))
+	descriptions := map[string]string{}
+	for i, newMode := range modes {
+		m := newMode()
+		btn := ui.NewButton(m.Name(), 100, float32(35+i*30), 16, 2, g.font, color.White, color.Black, color.Black)
+		btn.AddClickEvent(func(b *ui.Button) { g.startMode(newMode) })
+		layout.AddButton(m.Name(), btn)
+		descriptions[m.Name()] = m.Description()
+	}
+	players := ui.NewButton(fmt.Sprintf("Players: %v", len(g.players())), 220, 35, 12, 2, g.font, color.White, color.Black, color.Black)
+	players.AddClickEvent(func(b *ui.Button) {
+		//players and their hud are made in Init
+		g.coop = !g.coop
+		g.Init()
+		g.state = States.ModeSelect
+	})
+	layout.AddButton("Players", players)
+	descriptions["Players"] = "one player or local co-op"
+	mapbtn := ui.NewButton("Map: "+g.mapName(), 220, 65, 12, 2, g.font, color.White, color.Black, color.Black)
+	mapbtn.AddClickEvent(func(b *ui.Button) {
+		//map is made in Init
+		i := slices.Index(mapChoices, g.mapName())
+		g.setMap(mapChoices[(i+1)%len(mapChoices)])
+		g.Init()
+		g.state = States.ModeSelect
+	})
+	layout.AddButton("Map", mapbtn)
+	descriptions["Map"] = "generator of the map or endless map made in chunks"
+	diffbtn := ui.NewButton("Difficulty: "+g.difficulty.String(), 220, 95, 12, 2, g.font, color.White, color.Black, color.Black)
+	diffbtn.AddClickEvent(func(b *ui.Button) {
+		//button text is made in Init
+		g.difficulty = (g.difficulty + 1) % Difficulty(len(difficultyNames))
+		g.Init()
+		g.state = States.ModeSelect
+	})
+	layout.AddButton("Difficulty", diffbtn)
+	descriptions["Difficulty"] = "how well snipers aim and how long they charge"
+	back := ui.NewButton("Back", 100, float32(35+len(modes)*30), 16, 2, g.font, color.White, color.Black, color.Black)
+	back.AddClickEvent(func(b *ui.Button) { g.state = States.Menu })
+	layout.AddButton("Back", back)
+	layout.AddLabel("description", ui.NewLabel("", 10, 220, g.font, 12, color.RGBA{0, 100, 255, 255}))
+	layout.ApplyHoverToAllButtons(onhover)
+	g.modeDescriptions = descriptions
+	return layout
+}
+
+// maps the Map button cycles through . generators then the chunked map
+var mapChoices = []string{"caves", "rooms", "noise", "random", "endless"}
+
+func (g *Game) mapName() string {
+	if g.chunkedMap {
+		return "endless"
+	}
+	return g.mapGen.Generator
+}
+
+// picks a generator or the endless chunked map . other settings are kept
+func (g *Game) setMap(name string) {
+	g.chunkedMap = name == "endless"
+	if !g.chunkedMap {
+		g.mapGen.Generator = name
+	}
+}
+func (g *Game) updateModeSelect() {
+	layout := g.ui[States.ModeSelect]
+	layout.Update()
+	label, _ := layout.GetLabel("description")
+	label.SetText(g.modeDescriptions[layout.GetFocusedButton()])
+}
+
+// current endless game . survive as long as possible
+type Endless struct{}
+
+func NewEndless() Mode                    { return &Endless{} }
+func (Endless) Name() string              { return "Endless" }
+func (Endless) Description() string       { return "survive as long as you can" }
+func (Endless) Spawns() SpawnConfig       { return SpawnConfig{Interval: SPAWN_TIME, BossWaves: true} }
+func (Endless) Start(g *Game)             {}
+func (Endless) Update(g *Game) ModeResult { return Playing }
+
+const TIME_ATTACK_TIME = 180 //seconds
+
+// highest score before time runs out
+type TimeAttack struct {
+	time utils.Timer
+}
+
+func NewTimeAttack() Mode              { return &TimeAttack{time: utils.NewTimer(TIME_ATTACK_TIME)} }
+func (TimeAttack) Name() string        { return "Time Attack" }
+func (TimeAttack) Description() string { return "highest score in 3 minutes" }
+func (TimeAttack) Spawns() SpawnConfig { return SpawnConfig{Interval: 2, BossWaves: true} }
+func (m *TimeAttack) Start(g *Game) {
+	g.ui[States.Main].AddLabel("time", ui.NewLabel("", 250, 24, g.font, 16, color.RGBA{0, 100, 255, 255}))
+}
+func (m *TimeAttack) Update(g *Game) ModeResult {
+	m.time.UpdateTimer()
+	//rounded up so the clock shows 0:00 only when time is up
+	left := max(int(math.Ceil(float64(m.time.Time-m.time.GetCurrentTime()))), 0)
+	label, _ := g.ui[States.Main].GetLabel("time")
+	label.SetText(fmt.Sprintf("%v:%02v", left/60, left%60))
+	if m.time.GetCurrentTime() < m.time.Time {
+		return Playing
+	}
+	best := max(g.bestScores[m.Name()], g.score)
+	g.bestScores[m.Name()] = best
+	g.showBanner(fmt.Sprintf("TIME UP %v (BEST %v)", g.score, best), 5)
+	return Won
+}
+
+const SURVIVAL_WAVES = 10
+
+// survive a fixed number of waves
+type SurvivalWaves struct{}
+
+func NewSurvivalWaves() Mode       { return &SurvivalWaves{} }
+func (SurvivalWaves) Name() string { return "Survival Waves" }
+func (SurvivalWaves) Description() string {
+	return fmt.Sprintf("survive %v waves and their bosses", SURVIVAL_WAVES)
+}
+func (SurvivalWaves) Spawns() SpawnConfig { return SpawnConfig{Interval: 2.5, BossWaves: true} }
+func (SurvivalWaves) Start(g *Game)       {}
+func (SurvivalWaves) Update(g *Game) ModeResult {
+	label, _ := g.ui[States.Main].GetLabel("wave")
+	label.SetText(fmt.Sprintf("wave: %v/%v", g.wave, SURVIVAL_WAVES))
+	if g.wave > SURVIVAL_WAVES {
+		g.showBanner("ALL WAVES SURVIVED", 3)
+		return Won
+	}
+	return Playing
+}
+
+// endless where any damage kills
+type Hardcore struct{}
+
+func NewHardcore() Mode              { return &Hardcore{} }
+func (Hardcore) Name() string        { return "Hardcore" }
+func (Hardcore) Description() string { return "endless but one hit kills" }
+func (Hardcore) Spawns() SpawnConfig { return SpawnConfig{Interval: SPAWN_TIME, BossWaves: true} }
+func (Hardcore) Start(g *Game) {
+	g.oneHitKills = true
+	g.ui[States.Main].AddLabel("hardcore", ui.NewLabel("HARDCORE", 250, 24, g.font, 16, color.RGBA{255, 0, 100, 255}))
+}
+func (Hardcore) Update(g *Game) ModeResult { return Playing }
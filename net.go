@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/netplay"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	SNAPSHOT_TICKS = 2  //server ticks between snapshots
+	MAX_LAG_TICKS  = 20 //shots of lagging players are rewound at most this many ticks
+	DIAL_TIMEOUT   = 5 * time.Second
+)
+
+// entity types sent in snapshots . index is the Kind of the entity state
+var netKinds = []string{"player", "enemy", "boss", "bullet", "pickup"}
+
+// colors of online players in join order
+var netPlayerColors = []color.RGBA{{128, 0, 129, 255}, SecondPlayerColor, {200, 120, 0, 255}, {60, 160, 60, 255}}
+
+// flags of an entity state
+const (
+	netDowned uint8 = 1 << iota
+	netInvulnerable
+)
+
+// entities the server can put in snapshots
+type netEntity interface {
+	Entity
+	rect() utils.Rect
+	Color() color.Color
+}
+
+// state of the headless server
+type netServer struct {
+	srv     *netplay.Server
+	peers   map[uint32]*netplay.Peer
+	players map[uint32]*Player
+	ids     map[Entity]uint32 //ids of entities sent in snapshots
+	byID    map[uint32]Entity
+	nextID  uint32
+	history netplay.History //rects of enemies in the last ticks to test shots of lagging players against
+}
+
+// runs the game without a window taking players from the network until the process ends
+func runServer(addr string, conditions netplay.Conditions) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	game.server = &netServer{srv: netplay.NewServer(netplay.NewLossyConn(conn, conditions)),
+		peers: make(map[uint32]*netplay.Peer), players: make(map[uint32]*Player), ids: make(map[Entity]uint32),
+		byID: make(map[uint32]Entity), nextID: 1}
+	defer game.server.srv.Close()
+	//players go down instead of dying like local co-op
+	game.coop = true
+	game.Init()
+	if game.Tilemap.chunked {
+		return fmt.Errorf("chunked maps cant be played online")
+	}
+	game.startMode(NewEndless)
+	log.Println("server listening on", conn.LocalAddr())
+	ticker := time.NewTicker(time.Second / time.Duration(ebiten.TPS()))
+	defer ticker.Stop()
+	for range ticker.C {
+		game.updateServer()
+	}
+	return nil
+}
+
+// one tick of the server . the world is paused while nobody is connected
+func (g *Game) updateServer() {
+	s := g.server
+	joined, left := s.srv.Poll(ebiten.TPS())
+	for _, peer := range joined {
+		log.Printf("%v joined from %v", peer.Name, peer.Addr)
+		s.peers[peer.ID] = peer
+	}
+	for _, peer := range left {
+		log.Printf("%v left", peer.Name)
+		if p, ok := s.players[peer.ID]; ok {
+			p.Destroyed = true
+		}
+		delete(s.peers, peer.ID)
+		delete(s.players, peer.ID)
+	}
+	s.spawnPlayers()
+	for id, peer := range s.peers {
+		in := peer.NextInput()
+		p := s.players[id]
+		p.controls.Remote = &in
+		oldest := uint32(max(0, int(s.srv.Tick)-MAX_LAG_TICKS))
+		p.viewTick = min(s.srv.Tick, max(oldest, in.ViewTick))
+	}
+	if len(s.peers) > 0 {
+		g.updateWorld()
+	}
+	s.track()
+	s.srv.Tick++
+	if s.srv.Tick%SNAPSHOT_TICKS == 0 {
+		s.sendSnapshots()
+	}
+}
+
+// gives every peer a player . restarting the mode removes all players so they are made again
+func (s *netServer) spawnPlayers() {
+	for id := range s.peers {
+		if p, ok := s.players[id]; ok && slices.Contains(game.entities["player"], Entity(p)) {
+			continue
+		}
+		start := utils.Vec2{X: 5, Y: 5}
+		if game.level != nil {
+			start = tilePos(game.level.PlayerStart.X, game.level.PlayerStart.Y)
+		}
+		n := len(game.entities["player"])
+		p := NewPlayer(start.X+float32(n*PLAYER_RECT_SIZE), start.Y)
+		p.color = netPlayerColors[int(id-1)%len(netPlayerColors)]
+		p.controls = Controls{Remote: &netplay.Input{}}
+		s.players[id] = p
+		game.AddEntity(p)
+	}
+}
+
+// gives ids to entities sent in snapshots and records where enemies are this tick
+func (s *netServer) track() {
+	ids := make(map[Entity]uint32)
+	byID := make(map[uint32]Entity)
+	rects := make(map[uint32]netplay.Rect)
+	for _, etype := range netKinds {
+		for _, e := range game.entities[etype] {
+			if e.IsDestroyed() {
+				continue
+			}
+			id, ok := s.ids[e]
+			if !ok {
+				id = s.nextID
+				s.nextID++
+			}
+			ids[e], byID[id] = id, e
+			if d, ok := e.(Damageable); ok && (etype == "enemy" || etype == "boss") {
+				r := d.rect()
+				rects[id] = netplay.Rect{X: float32(r.X), Y: float32(r.Y), W: float32(r.Width), H: float32(r.Height)}
+			}
+		}
+	}
+	//forget entities that are gone
+	s.ids, s.byID = ids, byID
+	s.history.Record(s.srv.Tick, rects)
+}
+
+// entities and tiles as seen by each peer
+func (s *netServer) sendSnapshots() {
+	snap := netplay.Snapshot{Score: int32(game.score), Wave: int32(game.wave),
+		Width: uint16(game.Tilemap.Width), Height: uint16(game.Tilemap.Height), Tiles: make([]uint8, len(game.Tilemap.Tiles))}
+	for i, t := range game.Tilemap.Tiles {
+		snap.Tiles[i] = uint8(t.Variant)
+	}
+	for kind, etype := range netKinds {
+		for _, e := range game.entities[etype] {
+			ne, ok := e.(netEntity)
+			if id, tracked := s.ids[e]; ok && tracked {
+				snap.Entities = append(snap.Entities, entityState(ne, id, uint8(kind)))
+			}
+		}
+	}
+	for id, peer := range s.peers {
+		p := s.players[id]
+		snap.You = s.ids[p]
+		snap.Mana = int16(p.mana)
+		snap.Status = p.CurrentWeapon().Status()
+		if err := s.srv.Send(peer, snap); err != nil {
+			log.Println(err)
+		}
+	}
+}
+func entityState(e netEntity, id uint32, kind uint8) netplay.EntityState {
+	r := e.rect()
+	cr, cg, cb, ca := e.Color().RGBA()
+	state := netplay.EntityState{ID: id, Kind: kind, X: float32(r.X), Y: float32(r.Y), W: uint8(r.Width), H: uint8(r.Height),
+		Color: [4]uint8{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8), uint8(ca >> 8)}}
+	switch e := e.(type) {
+	case *Player:
+		state.HP, state.MaxHP = int16(e.hp), int16(e.maxHp)
+		if e.downed {
+			state.Flags |= netDowned
+		}
+		if e.IsInvulnerable() {
+			state.Flags |= netInvulnerable
+		}
+	case *Boss:
+		state.HP, state.MaxHP = int16(e.hp), BOSS_HP
+	}
+	return state
+}
+
+// tests bullets just fired by a player who was shown tick viewTick against the enemies as they were then
+// each bullet flies through the ticks since viewTick and damages the first enemy it meets there
+// bullets that miss carry on from where they are against the enemies of now
+func (s *netServer) rewindShots(bullets []Entity, viewTick uint32) {
+	solid := func(r netplay.Rect) bool {
+		tile := game.Tilemap.GetTile(utils.Vec2{X: r.X, Y: r.Y})
+		return tile != nil && tile.Solid()
+	}
+	for _, e := range bullets {
+		b, ok := e.(*Bullet)
+		if !ok || b.IsEnemy() {
+			continue
+		}
+		r := b.rect()
+		shot := netplay.Rect{X: float32(r.X), Y: float32(r.Y), W: float32(r.Width), H: float32(r.Height)}
+		id, hit := s.history.Trace(viewTick, s.srv.Tick, shot, b.Dir.X*b.speed, b.Dir.Y*b.speed, solid)
+		if !hit {
+			continue
+		}
+		if target, ok := s.byID[id].(Damageable); ok && !target.IsDestroyed() && b.hit(target) {
+			target.TakeDamage(b.damage)
+		}
+	}
+}
+
+// state of a client connected to a server
+type netClient struct {
+	conn    *netplay.Client
+	weapon  int //slot of the weapon in hand
+	weapons int //number of weapon slots
+}
+
+// joins the server at addr . the map and entities come from its snapshots
+func (g *Game) connect(addr string, conditions netplay.Conditions) error {
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return err
+	}
+	c, err := netplay.Dial(netplay.NewLossyConn(conn, conditions), addr, "player", DIAL_TIMEOUT)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	g.client = &netClient{conn: c, weapons: len(DefaultWeapons())}
+	g.entities = make(map[string][]Entity)
+	g.state = States.Main
+	return nil
+}
+
+// sends local input and shows the interpolated state of the server
+func (g *Game) updateClient() {
+	c := g.client
+	if err := c.conn.Update(); err != nil {
+		log.Println(err)
+		c.conn.Close()
+		g.client = nil
+		g.Init()
+		return
+	}
+	g.frame++
+	c.weapon = FirstControls.SwitchWeapon(c.weapon, c.weapons)
+	mx, my := FirstControls.Move()
+	aim := cursorWorldPos()
+	in := netplay.Input{MoveX: int8(mx), MoveY: int8(my), AimX: aim.X, AimY: aim.Y, Weapon: int8(c.weapon)}
+	if FirstControls.FirePressed() {
+		in.Buttons |= netplay.Fire
+	}
+	if FirstControls.DashPressed() {
+		in.Buttons |= netplay.Dash
+	}
+	for i := range FirstControls.Abilities {
+		if FirstControls.AbilityPressed(i) {
+			in.Buttons |= netplay.AbilityButton(i)
+		}
+	}
+	if err := c.conn.SendInput(in); err != nil {
+		log.Println(err)
+	}
+	if state, ok := c.conn.State(); ok {
+		g.applySnapshot(state)
+	}
+	g.cam.Update()
+}
+
+// replaces tiles , entities and hud with the ones in the snapshot
+func (g *Game) applySnapshot(s netplay.Snapshot) {
+	g.score, g.wave = int(s.Score), int(s.Wave)
+	w, h := int(s.Width), int(s.Height)
+	if g.Tilemap.Width != w || g.Tilemap.Height != h || g.Tilemap.chunked {
+		g.Tilemap = &Tilemap{Width: w, Height: h, Tiles: make([]*Tile, w*h), Terrain: DefaultTerrain}
+		for i := range g.Tilemap.Tiles {
+			g.Tilemap.Tiles[i] = newTile(i%w, i/w, Variant(s.Tiles[i]))
+		}
+	}
+	for i, v := range s.Tiles {
+		g.Tilemap.Tiles[i].SetVariant(Variant(v))
+	}
+	g.entities = make(map[string][]Entity)
+	for _, e := range s.Entities {
+		if int(e.Kind) < len(netKinds) {
+			g.AddEntity(&Ghost{e})
+		}
+	}
+	layout := g.ui[States.Main]
+	label, _ := layout.GetLabel("score")
+	label.SetText(fmt.Sprintf("score: %v", g.score))
+	wlabel, _ := layout.GetLabel("wave")
+	wlabel.SetText(fmt.Sprintf("wave: %v", g.wave))
+	weapon, _ := layout.GetLabel("weapon")
+	weapon.SetText(s.Status)
+	mana, _ := layout.GetBar("mana")
+	mana.SetValue(int(s.Mana))
+	if you, ok := s.Entity(s.You); ok {
+		hp, _ := layout.GetBar("hp")
+		hp.SetValueAndMax(int(you.MaxHP), int(you.HP))
+		g.cam.Follow(utils.Vec2{X: you.X, Y: you.Y}, cursorWorldPos())
+		g.cam.Constrain(g.Tilemap.GetWidth(), g.Tilemap.GetHieght())
+	}
+}
+
+// entity from a snapshot shown by a client . the server runs the real one
+type Ghost struct {
+	netplay.EntityState
+}
+
+func (gh *Ghost) Update() {}
+func (gh *Ghost) Draw(screen *ebiten.Image) {
+	c := color.NRGBA{gh.Color[0], gh.Color[1], gh.Color[2], gh.Color[3]}
+	if gh.Flags&netInvulnerable != 0 {
+		c.A = 120
+	}
+	if gh.Flags&netDowned != 0 {
+		c = color.NRGBA{90, 90, 90, 255}
+	}
+	game.cam.FillRect(screen, gh.X, gh.Y, float32(gh.W), float32(gh.H), c)
+}
+func (gh *Ghost) IsDestroyed() bool {
+	return false
+}
+func (gh *Ghost) Type() string {
+	return netKinds[gh.Kind]
+}
@@ -0,0 +1,166 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"time"
+)
+
+const (
+	HELLO_RETRY      = 250 * time.Millisecond //handshake is sent again this often until answered
+	SERVER_TIMEOUT   = 5 * time.Second        //server not heard from for this long is gone
+	INPUT_REDUNDANCY = 4                      //inputs sent in each packet
+	INTERP_DELAY     = 6                      //ticks shown behind the newest snapshot so there is one to interpolate towards
+	SNAPSHOT_BUFFER  = 32                     //snapshots kept for interpolation
+)
+
+var ErrDisconnected = errors.New("disconnected from server")
+
+// Client is the connection of a player to a server
+type Client struct {
+	ID         uint32
+	TickRate   int
+	conn       net.PacketConn
+	server     net.Addr
+	packets    chan packet
+	seq        uint32
+	sent       []Input    //last INPUT_REDUNDANCY inputs
+	snapshots  []Snapshot //sorted by tick
+	renderTick float64    //server tick being shown . fractional between two snapshots
+	lastHeard  time.Time
+}
+
+// joins the server at addr . blocks until the server answers or timeout passes
+func Dial(conn net.PacketConn, addr string, name string, timeout time.Duration) (*Client, error) {
+	server, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, server: server, packets: make(chan packet, 256)}
+	go readLoop(conn, c.packets)
+	retry := time.NewTicker(HELLO_RETRY)
+	defer retry.Stop()
+	deadline := time.After(timeout)
+	for {
+		if err := send(conn, server, Hello{Version: Version, Name: name}); err != nil {
+			return nil, err
+		}
+		select {
+		case p, ok := <-c.packets:
+			if !ok {
+				return nil, ErrDisconnected
+			}
+			switch m := p.msg.(type) {
+			case Welcome:
+				c.ID, c.TickRate = m.ID, int(m.TickRate)
+				c.renderTick = float64(m.Tick)
+				c.lastHeard = time.Now()
+				return c, nil
+			case Reject:
+				return nil, fmt.Errorf("server refused to join: %v", m.Reason)
+			}
+		case <-retry.C:
+		case <-deadline:
+			return nil, fmt.Errorf("no answer from %v after %v", addr, timeout)
+		}
+	}
+}
+
+// sends in with the inputs before it . Seq and ViewTick are filled in
+func (c *Client) SendInput(in Input) error {
+	c.seq++
+	in.Seq = c.seq
+	in.ViewTick = uint32(c.renderTick)
+	c.sent = append(c.sent, in)
+	if len(c.sent) > INPUT_REDUNDANCY {
+		c.sent = c.sent[1:]
+	}
+	return send(c.conn, c.server, Inputs{Inputs: c.sent})
+}
+
+// handles packets received since the last call and moves the shown tick forward one frame
+// returns ErrDisconnected when the server left or stopped answering
+func (c *Client) Update() error {
+	for drained := false; !drained; {
+		select {
+		case p, ok := <-c.packets:
+			if !ok {
+				return ErrDisconnected
+			}
+			switch m := p.msg.(type) {
+			case Snapshot:
+				c.lastHeard = time.Now()
+				c.addSnapshot(m)
+			case Bye:
+				return ErrDisconnected
+			}
+		default:
+			drained = true
+		}
+	}
+	if time.Since(c.lastHeard) > SERVER_TIMEOUT {
+		return ErrDisconnected
+	}
+	c.advance()
+	return nil
+}
+func (c *Client) addSnapshot(s Snapshot) {
+	i, found := slices.BinarySearchFunc(c.snapshots, s.Tick, func(s Snapshot, tick uint32) int { return int(s.Tick) - int(tick) })
+	if found {
+		return
+	}
+	c.snapshots = slices.Insert(c.snapshots, i, s)
+	if len(c.snapshots) > SNAPSHOT_BUFFER {
+		c.snapshots = c.snapshots[1:]
+	}
+}
+
+// one tick per frame . drifts towards INTERP_DELAY behind the newest snapshot and jumps there if far off
+func (c *Client) advance() {
+	if len(c.snapshots) == 0 {
+		return
+	}
+	target := float64(c.snapshots[len(c.snapshots)-1].Tick) - INTERP_DELAY
+	c.renderTick++
+	if diff := target - c.renderTick; math.Abs(diff) > INTERP_DELAY*2 {
+		c.renderTick = target
+	} else {
+		c.renderTick += diff * 0.05
+	}
+}
+
+// snapshot at the shown tick with entities between the snapshots around it
+// false until the first snapshot arrives
+func (c *Client) State() (Snapshot, bool) {
+	if len(c.snapshots) == 0 {
+		return Snapshot{}, false
+	}
+	i := slices.IndexFunc(c.snapshots, func(s Snapshot) bool { return float64(s.Tick) > c.renderTick })
+	switch i {
+	case -1:
+		//nothing newer arrived . newest is shown until it does
+		return c.snapshots[len(c.snapshots)-1], true
+	case 0:
+		return c.snapshots[0], true
+	}
+	from, to := c.snapshots[i-1], c.snapshots[i]
+	t := float32((c.renderTick - float64(from.Tick)) / float64(to.Tick-from.Tick))
+	state := from
+	state.Entities = slices.Clone(from.Entities)
+	for j, e := range state.Entities {
+		if next, ok := to.Entity(e.ID); ok {
+			state.Entities[j].X = e.X + (next.X-e.X)*t
+			state.Entities[j].Y = e.Y + (next.Y-e.Y)*t
+		}
+	}
+	return state, true
+}
+
+// tells the server the client left and closes the connection
+func (c *Client) Close() error {
+	send(c.conn, c.server, Bye{})
+	return c.conn.Close()
+}
@@ -0,0 +1,81 @@
+package netplay
+
+import (
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+)
+
+// bad network conditions simulated on outgoing packets
+type Conditions struct {
+	Latency time.Duration //added to every packet
+	Jitter  time.Duration //random extra delay up to this . packets can arrive out of order
+	Loss    float64       //0-1 chance a packet is dropped
+}
+
+// LossyConn delays and drops packets it sends so play over a bad network can be tried on localhost
+type LossyConn struct {
+	net.PacketConn
+	Conditions
+}
+
+// wraps conn . returns conn itself when conditions are perfect
+func NewLossyConn(conn net.PacketConn, c Conditions) net.PacketConn {
+	if c == (Conditions{}) {
+		return conn
+	}
+	return &LossyConn{PacketConn: conn, Conditions: c}
+}
+func (c *LossyConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if rand.Float64() < c.Loss {
+		return len(p), nil
+	}
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += rand.N(c.Jitter)
+	}
+	if delay <= 0 {
+		return c.PacketConn.WriteTo(p, addr)
+	}
+	//caller can reuse p after WriteTo returns
+	data := slices.Clone(p)
+	time.AfterFunc(delay, func() { c.PacketConn.WriteTo(data, addr) })
+	return len(p), nil
+}
+
+type packet struct {
+	msg  any
+	addr net.Addr
+}
+
+// decodes packets from conn into packets until conn is closed
+// the game drains the channel in its update so no game state is touched from here
+func readLoop(conn net.PacketConn, packets chan<- packet) {
+	defer close(packets)
+	buf := make([]byte, MAX_PACKET+1)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		msg, err := Decode(buf[:n])
+		if err != nil {
+			//garbage from someone else on the port
+			continue
+		}
+		select {
+		case packets <- packet{msg, addr}:
+		default:
+			//game isnt keeping up . dropping is what udp would do anyway
+		}
+	}
+}
+func send(conn net.PacketConn, addr net.Addr, msg any) error {
+	data, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(data, addr)
+	return err
+}
@@ -0,0 +1,226 @@
+package netplay
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTickRate = 60
+
+// bad network used by the tests . bad enough that handshakes and inputs get lost
+var badNetwork = Conditions{Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 0.3}
+
+// udp socket on localhost sending through c . closed when the test ends unless the test closes it first
+func listen(t *testing.T, c Conditions) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewLossyConn(conn, c)
+}
+
+// runs s in the background until the test ends . peers that join are sent on the returned channel
+func serve(t *testing.T, s *Server) <-chan *Peer {
+	t.Helper()
+	joined := make(chan *Peer, MAX_PEERS+1)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Second / testTickRate):
+			}
+			peers, _ := s.Poll(testTickRate)
+			for _, p := range peers {
+				joined <- p
+			}
+			s.Tick++
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		wg.Wait()
+		s.Close()
+	})
+	return joined
+}
+
+func TestDialUnderLoss(t *testing.T) {
+	srv := NewServer(listen(t, badNetwork))
+	joined := serve(t, srv)
+	c, err := Dial(listen(t, badNetwork), srv.conn.LocalAddr().String(), "lossy", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.TickRate != testTickRate {
+		t.Errorf("tick rate %v want %v", c.TickRate, testTickRate)
+	}
+	p := <-joined
+	if p.ID != c.ID || p.Name != "lossy" {
+		t.Errorf("server has peer %v %q but client got id %v", p.ID, p.Name, c.ID)
+	}
+	//repeated hellos must not join the client twice
+	select {
+	case p := <-joined:
+		t.Errorf("client joined again as peer %v", p.ID)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestRejectVersionMismatch(t *testing.T) {
+	srv := NewServer(listen(t, Conditions{}))
+	serve(t, srv)
+	conn := listen(t, Conditions{})
+	if err := send(conn, srv.conn.LocalAddr(), Hello{Version: Version + 1, Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, MAX_PACKET)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := Decode(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := msg.(Reject); !ok || !strings.Contains(r.Reason, "version") {
+		t.Errorf("got %+v want a Reject about the version", msg)
+	}
+}
+
+func TestRejectWhenFull(t *testing.T) {
+	srv := NewServer(listen(t, Conditions{}))
+	serve(t, srv)
+	addr := srv.conn.LocalAddr().String()
+	for i := range MAX_PEERS {
+		c, err := Dial(listen(t, Conditions{}), addr, "player", 2*time.Second)
+		if err != nil {
+			t.Fatalf("client %v: %v", i, err)
+		}
+		defer c.Close()
+	}
+	_, err := Dial(listen(t, Conditions{}), addr, "extra", 2*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "server is full") {
+		t.Errorf("got %v want server is full", err)
+	}
+}
+
+func TestInputsSurviveLoss(t *testing.T) {
+	srv := NewServer(listen(t, Conditions{}))
+	defer srv.Close()
+	//no jitter so the only thing hurting inputs is loss
+	conn := listen(t, Conditions{Loss: 0.2})
+	go func() {
+		for range 20 {
+			send(conn, srv.conn.LocalAddr(), Hello{Version: Version, Name: "player"})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	var peer *Peer
+	for deadline := time.Now().Add(2 * time.Second); peer == nil && time.Now().Before(deadline); {
+		joined, _ := srv.Poll(testTickRate)
+		if len(joined) > 0 {
+			peer = joined[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if peer == nil {
+		t.Fatal("client never joined")
+	}
+	c := &Client{conn: conn, server: srv.conn.LocalAddr()}
+	const sent = 200
+	applied := []uint32{}
+	drain := func() {
+		srv.Poll(testTickRate)
+		for len(peer.queued) > 0 {
+			applied = append(applied, peer.NextInput().Seq)
+		}
+	}
+	for range sent {
+		if err := c.SendInput(Input{}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		drain()
+	}
+	time.Sleep(50 * time.Millisecond)
+	drain()
+	for i := 1; i < len(applied); i++ {
+		if applied[i] <= applied[i-1] {
+			t.Fatalf("input %v applied after %v", applied[i], applied[i-1])
+		}
+	}
+	//an input is only lost when all INPUT_REDUNDANCY packets holding it are
+	if len(applied) < sent*95/100 {
+		t.Errorf("%v of %v inputs arrived with 20%% loss", len(applied), sent)
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+	p := &Peer{}
+	window := func(newest uint32) []Input {
+		ins := []Input{}
+		for seq := max(1, int(newest)-INPUT_REDUNDANCY+1); seq <= int(newest); seq++ {
+			ins = append(ins, Input{Seq: uint32(seq)})
+		}
+		return ins
+	}
+	//packet 2 is lost , 4 arrives before 3 and 6 before 5
+	for _, newest := range []uint32{1, 4, 3, 6, 5} {
+		p.queue(window(newest))
+	}
+	for want := uint32(1); want <= 6; want++ {
+		if got := p.NextInput().Seq; got != want {
+			t.Fatalf("got input %v want %v", got, want)
+		}
+	}
+	//nothing queued repeats the last input without its buttons
+	p.queue([]Input{{Seq: 7, MoveX: 1, Buttons: Fire}})
+	p.NextInput()
+	if in := p.NextInput(); in.Seq != 7 || in.MoveX != 1 || in.Buttons != 0 {
+		t.Errorf("got %+v want input 7 without buttons", in)
+	}
+}
+
+func TestQueueBacklog(t *testing.T) {
+	p := &Peer{}
+	for seq := uint32(1); seq <= INPUT_BACKLOG+5; seq++ {
+		p.queue([]Input{{Seq: seq}})
+	}
+	if got := p.NextInput().Seq; got != 6 {
+		t.Errorf("oldest input kept is %v want 6", got)
+	}
+}
+
+func TestStateInterpolates(t *testing.T) {
+	c := &Client{}
+	c.addSnapshot(Snapshot{Tick: 12, Entities: []EntityState{{ID: 1, X: 20, Y: 40}}})
+	c.addSnapshot(Snapshot{Tick: 10, Entities: []EntityState{{ID: 1, X: 0, Y: 0}, {ID: 2, X: 5, Y: 5}}})
+	c.renderTick = 11
+	s, ok := c.State()
+	if !ok {
+		t.Fatal("no state with two snapshots")
+	}
+	if e, _ := s.Entity(1); e.X != 10 || e.Y != 20 {
+		t.Errorf("entity at %v,%v want 10,20 half way between the snapshots", e.X, e.Y)
+	}
+	//gone from the newer snapshot so it stays where it was
+	if e, _ := s.Entity(2); e.X != 5 || e.Y != 5 {
+		t.Errorf("entity at %v,%v want 5,5", e.X, e.Y)
+	}
+	c.renderTick = 15
+	if s, _ := c.State(); s.Tick != 12 {
+		t.Errorf("past the newest snapshot shows tick %v want 12", s.Tick)
+	}
+}
@@ -0,0 +1,240 @@
+// Package netplay sends player inputs and game snapshots over udp
+//
+// the server runs the game and is the only one that changes it . clients send their inputs
+// every frame and show snapshots of the server interpolated a little in the past
+package netplay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	Version    = 1
+	MAX_PACKET = 65000 //bytes . bigger snapshots cant be sent
+)
+
+// first byte of every packet
+type MsgType uint8
+
+const (
+	MsgHello    MsgType = iota + 1 //client asks to join
+	MsgWelcome                     //server accepted the client
+	MsgReject                      //server refused the client
+	MsgInputs                      //latest inputs of a client
+	MsgSnapshot                    //state of the game for one client
+	MsgBye                         //either side left
+)
+
+// handshake sent by the client until it gets a Welcome or Reject
+type Hello struct {
+	Version uint16
+	Name    string
+}
+type Welcome struct {
+	ID       uint32 //id of the client on the server
+	Tick     uint32 //current tick of the server
+	TickRate uint16 //server ticks per second
+}
+type Reject struct {
+	Reason string
+}
+type Bye struct{}
+
+// buttons of an input
+const (
+	Fire uint8 = 1 << iota
+	Dash
+)
+
+// returns the button bit of ability i
+func AbilityButton(i int) uint8 {
+	return Dash << (i + 1)
+}
+
+// what a client did in one frame
+type Input struct {
+	Seq          uint32 //counts up every frame . server applies each input once in order
+	ViewTick     uint32 //server tick the client was showing . used to make up for latency
+	MoveX, MoveY int8
+	AimX, AimY   float32 //world position aimed at
+	Buttons      uint8
+	Weapon       int8 //slot of the weapon in hand
+}
+
+func (in Input) Pressed(button uint8) bool {
+	return in.Buttons&button != 0
+}
+
+// inputs are sent with the few before them so a lost packet doesnt lose input
+type Inputs struct {
+	Inputs []Input
+}
+
+// one entity in a snapshot
+type EntityState struct {
+	ID        uint32 //same entity keeps its id between snapshots
+	Kind      uint8
+	X, Y      float32
+	W, H      uint8
+	Color     [4]uint8
+	HP, MaxHP int16
+	Flags     uint8
+}
+
+// state of the game sent to a client
+type Snapshot struct {
+	Tick          uint32
+	Ack           uint32 //last input of the client applied
+	You           uint32 //entity id of the player of the client
+	Score, Wave   int32
+	Mana          int16
+	Status        string //weapon status of the player of the client
+	Width, Height uint16 //map size in tiles
+	Tiles         []uint8
+	Entities      []EntityState
+}
+
+// returns the entity with id or false if its not in the snapshot
+func (s *Snapshot) Entity(id uint32) (EntityState, bool) {
+	for _, e := range s.Entities {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return EntityState{}, false
+}
+
+var ErrUnknownMessage = errors.New("unknown message")
+
+// turns a message into a packet
+func Encode(msg any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := func(data any) {
+		binary.Write(buf, binary.LittleEndian, data)
+	}
+	switch m := msg.(type) {
+	case Hello:
+		w(MsgHello)
+		w(m.Version)
+		writeString(buf, m.Name)
+	case Welcome:
+		w(MsgWelcome)
+		w(m)
+	case Reject:
+		w(MsgReject)
+		writeString(buf, m.Reason)
+	case Inputs:
+		w(MsgInputs)
+		w(uint8(len(m.Inputs)))
+		w(m.Inputs)
+	case Snapshot:
+		w(MsgSnapshot)
+		for _, v := range []any{m.Tick, m.Ack, m.You, m.Score, m.Wave, m.Mana} {
+			w(v)
+		}
+		writeString(buf, m.Status)
+		w(m.Width)
+		w(m.Height)
+		buf.Write(m.Tiles)
+		w(uint16(len(m.Entities)))
+		w(m.Entities)
+	case Bye:
+		w(MsgBye)
+	default:
+		return nil, fmt.Errorf("%w %T", ErrUnknownMessage, msg)
+	}
+	if buf.Len() > MAX_PACKET {
+		return nil, fmt.Errorf("packet of %v bytes is bigger than %v", buf.Len(), MAX_PACKET)
+	}
+	return buf.Bytes(), nil
+}
+
+// turns a packet back into the message it was made from
+func Decode(data []byte) (any, error) {
+	r := bytes.NewReader(data)
+	var err error
+	read := func(data any) {
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, data)
+		}
+	}
+	var t MsgType
+	read(&t)
+	var msg any
+	switch t {
+	case MsgHello:
+		m := Hello{}
+		read(&m.Version)
+		if err == nil {
+			m.Name, err = readString(r)
+		}
+		msg = m
+	case MsgWelcome:
+		m := Welcome{}
+		read(&m)
+		msg = m
+	case MsgReject:
+		m := Reject{}
+		m.Reason, err = readString(r)
+		msg = m
+	case MsgInputs:
+		var n uint8
+		read(&n)
+		m := Inputs{Inputs: make([]Input, n)}
+		read(m.Inputs)
+		msg = m
+	case MsgSnapshot:
+		m := Snapshot{}
+		read(&m.Tick)
+		read(&m.Ack)
+		read(&m.You)
+		read(&m.Score)
+		read(&m.Wave)
+		read(&m.Mana)
+		if err == nil {
+			m.Status, err = readString(r)
+		}
+		read(&m.Width)
+		read(&m.Height)
+		if err == nil && int(m.Width)*int(m.Height) > r.Len() {
+			return nil, fmt.Errorf("snapshot of %vx%v tiles is cut short", m.Width, m.Height)
+		}
+		m.Tiles = make([]uint8, int(m.Width)*int(m.Height))
+		read(m.Tiles)
+		var n uint16
+		read(&n)
+		m.Entities = make([]EntityState, n)
+		read(m.Entities)
+		msg = m
+	case MsgBye:
+		msg = Bye{}
+	default:
+		return nil, fmt.Errorf("%w %v", ErrUnknownMessage, t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("message %v: %w", t, err)
+	}
+	return msg, nil
+}
+
+// strings are sent as their length then their bytes
+func writeString(buf *bytes.Buffer, s string) {
+	s = s[:min(len(s), 255)]
+	buf.WriteByte(uint8(len(s)))
+	buf.WriteString(s)
+}
+func readString(r *bytes.Reader) (string, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
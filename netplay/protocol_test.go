@@ -0,0 +1,85 @@
+package netplay
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	msgs := []any{
+		Hello{Version: Version, Name: "player"},
+		Welcome{ID: 3, Tick: 1200, TickRate: 60},
+		Reject{Reason: "server is full"},
+		Inputs{Inputs: []Input{
+			{Seq: 1, ViewTick: 10, MoveX: -1, MoveY: 1, AimX: 12.5, AimY: -3, Buttons: Fire | AbilityButton(1), Weapon: 2},
+			{Seq: 2, ViewTick: 11, Buttons: Dash},
+		}},
+		Snapshot{Tick: 40, Ack: 7, You: 2, Score: 150, Wave: 3, Mana: 80, Status: "rifle 5/10",
+			Width: 3, Height: 2, Tiles: []uint8{0, 1, 2, 3, 4, 5},
+			Entities: []EntityState{
+				{ID: 1, Kind: 0, X: 5, Y: 6, W: 16, H: 16, Color: [4]uint8{128, 0, 129, 255}, HP: 90, MaxHP: 100, Flags: 1},
+				{ID: 2, Kind: 3, X: -1.5, Y: 300, W: 8, H: 8},
+			}},
+		Bye{},
+	}
+	for _, msg := range msgs {
+		data, err := Encode(msg)
+		if err != nil {
+			t.Fatalf("encode %T: %v", msg, err)
+		}
+		got, err := Decode(data)
+		if err != nil {
+			t.Fatalf("decode %T: %v", msg, err)
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("%T changed in round trip\nsent %+v\n got %+v", msg, msg, got)
+		}
+	}
+}
+
+func TestEncodeUnknownMessage(t *testing.T) {
+	if _, err := Encode(struct{}{}); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("got %v want ErrUnknownMessage", err)
+	}
+	if _, err := Decode([]byte{99}); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("got %v want ErrUnknownMessage", err)
+	}
+}
+
+func TestDecodeTruncatedSnapshot(t *testing.T) {
+	data, err := Encode(Snapshot{Status: "pistol", Width: 4, Height: 4, Tiles: make([]uint8, 16),
+		Entities: []EntityState{{ID: 1}, {ID: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//every cut ends somewhere inside the header , status , tiles or entities
+	for n := 1; n < len(data); n++ {
+		if _, err := Decode(data[:n]); err == nil {
+			t.Errorf("snapshot cut to %v of %v bytes decoded without error", n, len(data))
+		}
+	}
+}
+
+func TestDecodeTruncatedString(t *testing.T) {
+	data, err := Encode(Reject{Reason: "server is full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(data[:len(data)-3]); err == nil {
+		t.Error("reject with its reason cut short decoded without error")
+	}
+}
+
+func TestDecodeHugeTileCount(t *testing.T) {
+	data, err := Encode(Snapshot{Width: 1, Height: 1, Tiles: []uint8{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//packet ends with width , height , one tile and the entity count . claim a map far bigger than the packet
+	i := len(data) - 2 - 1 - 4
+	data[i], data[i+1], data[i+2], data[i+3] = 0xff, 0xff, 0xff, 0xff
+	if _, err := Decode(data); err == nil {
+		t.Error("snapshot with more tiles than bytes decoded without error")
+	}
+}
@@ -0,0 +1,64 @@
+package netplay
+
+const HISTORY_TICKS = 32 //ticks of target rects kept for rewinding shots
+
+// box in world pixels
+type Rect struct {
+	X, Y, W, H float32
+}
+
+func (r Rect) Overlaps(o Rect) bool {
+	return r.X < o.X+o.W && o.X < r.X+r.W && r.Y < o.Y+o.H && o.Y < r.Y+r.H
+}
+
+// History keeps where targets were in the last HISTORY_TICKS ticks of the server
+// so shots of a lagging client are tested against what it was shown instead of where targets are now
+type History struct {
+	ticks [HISTORY_TICKS]recorded
+}
+type recorded struct {
+	tick  uint32
+	rects map[uint32]Rect //by entity id
+}
+
+// stores the rects of targets at tick . call once a tick after the world updated
+func (h *History) Record(tick uint32, rects map[uint32]Rect) {
+	if rects == nil {
+		rects = map[uint32]Rect{}
+	}
+	h.ticks[tick%HISTORY_TICKS] = recorded{tick, rects}
+}
+
+// rects of targets at tick or false if it wasnt recorded or is too old
+func (h *History) At(tick uint32) (map[uint32]Rect, bool) {
+	r := h.ticks[tick%HISTORY_TICKS]
+	if r.rects == nil || r.tick != tick {
+		return nil, false
+	}
+	return r.rects, true
+}
+
+// moves shot by dx,dy a tick from the tick the client saw up to now
+// testing it against the targets as they were at each tick
+// returns the id of the first target hit . ties go to the lowest id so the result doesnt depend on map order
+// stops without a hit when blocked returns true or the ticks run past the history
+func (h *History) Trace(from, now uint32, shot Rect, dx, dy float32, blocked func(Rect) bool) (uint32, bool) {
+	for tick := from; tick < now; tick++ {
+		rects, ok := h.At(tick)
+		if !ok || (blocked != nil && blocked(shot)) {
+			return 0, false
+		}
+		hit, found := uint32(0), false
+		for id, r := range rects {
+			if shot.Overlaps(r) && (!found || id < hit) {
+				hit, found = id, true
+			}
+		}
+		if found {
+			return hit, true
+		}
+		shot.X += dx
+		shot.Y += dy
+	}
+	return 0, false
+}
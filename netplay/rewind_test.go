@@ -0,0 +1,128 @@
+package netplay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceRewinds(t *testing.T) {
+	h := &History{}
+	//target walks right one pixel a tick
+	for tick := uint32(0); tick < 40; tick++ {
+		h.Record(tick, map[uint32]Rect{1: {X: float32(tick), Y: 0, W: 4, H: 4}})
+	}
+	shot := Rect{X: 12, Y: 1, W: 1, H: 1}
+	if id, ok := h.Trace(10, 39, shot, 0, 0, nil); !ok || id != 1 {
+		t.Errorf("shot where the target was at tick 10 missed")
+	}
+	if _, ok := h.Trace(20, 39, shot, 0, 0, nil); ok {
+		t.Errorf("shot where the target was at tick 10 hit it at tick 20")
+	}
+	//moving shot meets the target on the way
+	if _, ok := h.Trace(20, 39, Rect{X: 40, Y: 1, W: 1, H: 1}, -2, 0, nil); !ok {
+		t.Errorf("shot flying into the target missed")
+	}
+	if _, ok := h.Trace(20, 39, Rect{X: 40, Y: 1, W: 1, H: 1}, -2, 0, func(Rect) bool { return true }); ok {
+		t.Errorf("blocked shot hit")
+	}
+	//ticks older than the history are gone
+	if _, ok := h.At(39 - HISTORY_TICKS); ok {
+		t.Errorf("tick %v is still kept", 39-HISTORY_TICKS)
+	}
+}
+
+func TestTraceLowestID(t *testing.T) {
+	h := &History{}
+	h.Record(5, map[uint32]Rect{7: {W: 4, H: 4}, 3: {W: 4, H: 4}, 9: {W: 4, H: 4}})
+	if id, _ := h.Trace(5, 6, Rect{X: 1, Y: 1, W: 1, H: 1}, 0, 0, nil); id != 3 {
+		t.Errorf("hit %v want 3", id)
+	}
+}
+
+// a client behind a slow network shoots at a moving enemy where it sees it
+// the server rewinds to the tick the client saw and the shot hits though the enemy moved on since
+func TestLaggedShotHits(t *testing.T) {
+	lag := Conditions{Latency: 60 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 0.1}
+	srv := NewServer(listen(t, lag))
+	defer srv.Close()
+	enemy := func(tick uint32) Rect {
+		return Rect{X: 100 + float32(tick), Y: 50, W: 16, H: 16}
+	}
+	history := &History{}
+	peers := []*Peer{}
+	shots, hits, hitsNow := 0, 0, 0
+	tick := func() {
+		joined, _ := srv.Poll(testTickRate)
+		peers = append(peers, joined...)
+		now := enemy(srv.Tick)
+		for _, p := range peers {
+			in := p.NextInput()
+			if !in.Pressed(Fire) {
+				continue
+			}
+			shot := Rect{X: in.AimX, Y: in.AimY, W: 1, H: 1}
+			shots++
+			if _, ok := history.Trace(in.ViewTick, srv.Tick, shot, 0, 0, nil); ok {
+				hits++
+			}
+			if shot.Overlaps(now) {
+				hitsNow++
+			}
+		}
+		history.Record(srv.Tick, map[uint32]Rect{1: now})
+		if srv.Tick%2 == 0 {
+			for _, p := range peers {
+				srv.Send(p, Snapshot{Entities: []EntityState{{ID: 1, X: now.X, Y: now.Y, W: uint8(now.W), H: uint8(now.H)}}})
+			}
+		}
+		srv.Tick++
+	}
+	dialed := make(chan *Client)
+	go func() {
+		c, err := Dial(listen(t, lag), srv.conn.LocalAddr().String(), "lagging", 5*time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		dialed <- c
+	}()
+	var c *Client
+	for c == nil {
+		select {
+		case c = <-dialed:
+			if c == nil {
+				return
+			}
+		case <-time.After(time.Second / testTickRate):
+			tick()
+		}
+	}
+	defer c.Close()
+	for range testTickRate * 2 {
+		if err := c.Update(); err != nil {
+			t.Fatal(err)
+		}
+		in := Input{}
+		if s, ok := c.State(); ok {
+			if e, ok := s.Entity(1); ok {
+				in.AimX, in.AimY = e.X+float32(e.W)/2, e.Y+float32(e.H)/2
+				in.Buttons = Fire
+			}
+		}
+		if err := c.SendInput(in); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second / testTickRate)
+		tick()
+	}
+	t.Logf("%v shots . %v hit after rewinding . %v hit the enemy where it is now", shots, hits, hitsNow)
+	if shots < testTickRate {
+		t.Fatalf("only %v shots arrived", shots)
+	}
+	if hits < shots*9/10 {
+		t.Errorf("%v of %v shots hit after rewinding", hits, shots)
+	}
+	//without rewinding the enemy has walked away from where the client saw it
+	if hitsNow > shots/10 {
+		t.Errorf("%v of %v shots hit the enemy where it is now . lag is too small to show rewinding", hitsNow, shots)
+	}
+}
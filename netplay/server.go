@@ -0,0 +1,144 @@
+package netplay
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	MAX_PEERS     = 4
+	PEER_TIMEOUT  = 5 * time.Second //peers not heard from for this long are dropped
+	INPUT_BACKLOG = 8               //queued inputs past this are skipped so a lagging peer catches up
+)
+
+// Peer is a client connected to the server
+type Peer struct {
+	ID       uint32
+	Name     string
+	Addr     net.Addr
+	lastSeen time.Time
+	queued   []Input //received and not applied yet in seq order
+	last     Input   //last applied input
+}
+
+// Server accepts clients and collects their inputs . the game runs the simulation and sends snapshots
+type Server struct {
+	Tick    uint32 //counted up by the game every update
+	conn    net.PacketConn
+	packets chan packet
+	peers   map[string]*Peer
+	nextID  uint32
+}
+
+// starts reading packets from conn . conn is closed by Close
+func NewServer(conn net.PacketConn) *Server {
+	s := &Server{conn: conn, packets: make(chan packet, 256), peers: make(map[string]*Peer), nextID: 1}
+	go readLoop(conn, s.packets)
+	return s
+}
+
+// handles packets received since the last call
+// returns peers that finished the handshake and peers that left or timed out
+func (s *Server) Poll(tickRate int) (joined, left []*Peer) {
+	for {
+		var p packet
+		select {
+		case p = <-s.packets:
+		default:
+			return joined, append(left, s.timedOut()...)
+		}
+		peer := s.peers[p.addr.String()]
+		switch m := p.msg.(type) {
+		case Hello:
+			if peer == nil {
+				if reason := s.refuse(m); reason != "" {
+					send(s.conn, p.addr, Reject{Reason: reason})
+					continue
+				}
+				peer = &Peer{ID: s.nextID, Name: m.Name, Addr: p.addr}
+				s.nextID++
+				s.peers[p.addr.String()] = peer
+				joined = append(joined, peer)
+			}
+			//welcome is sent again if the first one was lost
+			peer.lastSeen = time.Now()
+			send(s.conn, p.addr, Welcome{ID: peer.ID, Tick: s.Tick, TickRate: uint16(tickRate)})
+		case Inputs:
+			if peer != nil {
+				peer.lastSeen = time.Now()
+				peer.queue(m.Inputs)
+			}
+		case Bye:
+			if peer != nil {
+				delete(s.peers, p.addr.String())
+				left = append(left, peer)
+			}
+		}
+	}
+}
+
+// reason a new client cant join or empty if it can
+func (s *Server) refuse(h Hello) string {
+	if h.Version != Version {
+		return fmt.Sprintf("server runs version %v but client runs %v", Version, h.Version)
+	}
+	if len(s.peers) >= MAX_PEERS {
+		return "server is full"
+	}
+	return ""
+}
+func (s *Server) timedOut() []*Peer {
+	left := []*Peer{}
+	for addr, p := range s.peers {
+		if time.Since(p.lastSeen) > PEER_TIMEOUT {
+			delete(s.peers, addr)
+			left = append(left, p)
+		}
+	}
+	return left
+}
+
+// adds inputs newer than the last queued one keeping them in seq order
+func (p *Peer) queue(inputs []Input) {
+	for _, in := range inputs {
+		newest := p.last.Seq
+		if len(p.queued) > 0 {
+			newest = p.queued[len(p.queued)-1].Seq
+		}
+		if in.Seq > newest {
+			p.queued = append(p.queued, in)
+		}
+	}
+	if len(p.queued) > INPUT_BACKLOG {
+		p.queued = p.queued[len(p.queued)-INPUT_BACKLOG:]
+	}
+}
+
+// returns the next input to apply
+// when none arrived the last one is repeated without its buttons so the player keeps moving but doesnt fire twice
+func (p *Peer) NextInput() Input {
+	if len(p.queued) == 0 {
+		in := p.last
+		in.Buttons = 0
+		return in
+	}
+	p.last = p.queued[0]
+	p.queued = p.queued[1:]
+	return p.last
+}
+
+// sends snap to peer acknowledging its last applied input
+func (s *Server) Send(peer *Peer, snap Snapshot) error {
+	snap.Tick = s.Tick
+	snap.Ack = peer.last.Seq
+	return send(s.conn, peer.Addr, snap)
+}
+
+// tells every peer the server is leaving and closes the connection
+func (s *Server) Close() error {
+	for _, p := range s.peers {
+		send(s.conn, p.Addr, Bye{})
+	}
+	return s.conn.Close()
+}
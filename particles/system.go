@@ -7,7 +7,6 @@ import (
 	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hasona23/game/utils"
 )
 
@@ -190,29 +189,31 @@ func (ps *ParticleSystem) moveParticles() {
 	}
 }
 func (ps ParticleSystem) Draw(screen *ebiten.Image) {
-	ps.drawWithOffset(screen, utils.Vec2{X: 0, Y: 0})
+	ps.draw(screen, ebiten.GeoM{})
 
 }
+
+// draws particles in the world through the camera
 func (ps ParticleSystem) DrawCam(screen *ebiten.Image, cam utils.Cam) {
-	ps.drawWithOffset(screen, utils.Vec2{X: cam.X, Y: cam.Y})
+	ps.draw(screen, cam.GeoM())
 }
-func (ps ParticleSystem) drawWithOffset(screen *ebiten.Image, offset utils.Vec2) {
+func (ps ParticleSystem) draw(screen *ebiten.Image, geoM ebiten.GeoM) {
 	if ps.ModelParticle.Img != nil {
 		op := &ebiten.DrawImageOptions{}
 		for _, p := range ps.Particles {
 			s := ps.ModelParticle.Img.Bounds()
 			op.GeoM.Translate(-float64(s.Dx())/2, -float64(s.Dy())/2)
 			op.GeoM.Rotate(float64(p.Angle))
-			op.GeoM.Translate(float64(p.X/p.Scale)+float64(offset.X), float64(p.Y/float32(p.Scale)+float32(offset.Y)))
 			op.GeoM.Scale(float64(p.Scale), float64(p.Scale))
+			op.GeoM.Translate(float64(p.X), float64(p.Y))
+			op.GeoM.Concat(geoM)
 			screen.DrawImage(p.Img, op)
 			op.GeoM.Reset()
 			fmt.Println("img")
 		}
 	} else {
 		for _, p := range ps.Particles {
-			vector.DrawFilledRect(screen, (p.X + float32(offset.X)), (p.Y + float32(offset.Y)), p.Scale, p.Scale, ps.ModelParticle.Color, false)
+			utils.FillRect(screen, geoM, p.X, p.Y, p.Scale, p.Scale, ps.ModelParticle.Color)
 		}
 	}
-	//vector.StrokeRect(screen, float32(ps.Area.X)+offset.X, float32(ps.Area.Y)+offset.Y, float32(ps.Area.Width), float32(ps.Area.Height), 10, color.Black, false)
 }
@@ -0,0 +1,112 @@
+package patterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+)
+
+type Kind string
+
+const (
+	Spread  Kind = "spread"  //shots spread evenly over an arc at the same time
+	Ring    Kind = "ring"    //shots in all directions at the same time
+	Spiral  Kind = "spiral"  //shots one after another rotating each time
+	Burst   Kind = "burst"   //shots one after another in the same direction
+	Fan     Kind = "fan"     //shots one after another sweeping across an arc
+	Scatter Kind = "scatter" //shots in random directions and speeds inside an arc
+)
+
+// Pattern describes how a group of bullets is fired
+//
+// Angle depends on kind:
+// spread,fan,scatter : width of the arc in degrees
+// ring : rotation of the ring in degrees
+// spiral : degrees added between each shot
+// burst : not used
+type Pattern struct {
+	Kind  Kind    `json:"kind"`
+	Count int     `json:"count"`
+	Angle float64 `json:"angle"`
+	Speed float32 `json:"speed"`
+	Delay float32 `json:"delay"` //seconds between shots for spiral,burst and fan
+}
+
+// a single bullet of a pattern
+type Shot struct {
+	Angle float64 //radians
+	Speed float32
+	Delay float32 //seconds after the pattern started
+}
+
+// returns the shots of the pattern around aim (radians)
+func (p Pattern) Shots(aim float64) []Shot {
+	shots := make([]Shot, 0, p.Count)
+	arc := p.Angle * math.Pi / 180
+	for i := range p.Count {
+		shot := Shot{Angle: aim, Speed: p.Speed}
+		// position of shot from 0 to 1 across the arc
+		t := 0.5
+		if p.Count > 1 {
+			t = float64(i) / float64(p.Count-1)
+		}
+		switch p.Kind {
+		case Spread:
+			shot.Angle = aim - arc/2 + arc*t
+		case Ring:
+			shot.Angle = aim + arc + 2*math.Pi*float64(i)/float64(p.Count)
+		case Spiral:
+			shot.Angle = aim + arc*float64(i)
+			shot.Delay = p.Delay * float32(i)
+		case Burst:
+			shot.Delay = p.Delay * float32(i)
+		case Fan:
+			shot.Angle = aim - arc/2 + arc*t
+			shot.Delay = p.Delay * float32(i)
+		case Scatter:
+			shot.Angle = aim - arc/2 + arc*rand.Float64()
+			shot.Speed = p.Speed * (0.75 + rand.Float32()*0.5)
+		}
+		shots = append(shots, shot)
+	}
+	return shots
+}
+
+// checks the pattern has valid values
+func (p Pattern) Validate() error {
+	switch p.Kind {
+	case Spread, Ring, Spiral, Burst, Fan, Scatter:
+	default:
+		return fmt.Errorf("unknown pattern kind %q", p.Kind)
+	}
+	if p.Count < 1 {
+		return fmt.Errorf("pattern count must be at least 1 got %v", p.Count)
+	}
+	if p.Speed <= 0 {
+		return fmt.Errorf("pattern speed must be more than 0 got %v", p.Speed)
+	}
+	if p.Delay < 0 {
+		return fmt.Errorf("pattern delay cant be negative got %v", p.Delay)
+	}
+	return nil
+}
+
+// loads named patterns from a json file
+func Load(path string) (map[string]Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make(map[string]Pattern)
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("patterns %v: %w", path, err)
+	}
+	for name, p := range patterns {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("patterns %v: %v: %w", path, name, err)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"image/color"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/particles"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	PICKUP_SIZE     = 10
+	PICKUP_LIFETIME = 10 //seconds before despawning
+	PICKUP_BLINK    = 3  //pickup blinks in the last seconds of its life
+	PICKUP_ATTRACT  = 48 //distance where pickups start moving to the player
+	PICKUP_SPEED    = 2.5
+	BOOST_TIME      = 5
+	MAX_SHIELD      = 3
+)
+
+type PickupKind int
+
+const (
+	HealthPickup PickupKind = iota
+	ManaPickup
+	BoostPickup
+	ShieldPickup
+	WeaponCrate
+)
+
+var pickupColors = map[PickupKind]color.RGBA{
+	HealthPickup: {255, 0, 100, 255},
+	ManaPickup:   {100, 0, 255, 255},
+	BoostPickup:  {255, 215, 0, 255},
+	ShieldPickup: ShieldColor,
+	WeaponCrate:  {120, 80, 40, 255},
+}
+
+// what an enemy can drop and how likely it is compared to the others
+type LootEntry struct {
+	Kind   PickupKind
+	Weight float32
+}
+
+// chance of dropping anything and what can be dropped
+type LootTable struct {
+	DropChance float32 //from 0 to 1
+	Entries    []LootEntry
+}
+
+// loot tables for each enemy archetype
+var lootTables = map[string]LootTable{
+	"bomber":   {0.15, []LootEntry{{HealthPickup, 40}, {ManaPickup, 50}, {BoostPickup, 10}}},
+	"sniper":   {0.3, []LootEntry{{HealthPickup, 30}, {ManaPickup, 30}, {WeaponCrate, 40}}},
+	"digger":   {0.5, []LootEntry{{ManaPickup, 40}, {WeaponCrate, 40}, {ShieldPickup, 20}}},
+	"splitter": {0.2, []LootEntry{{HealthPickup, 50}, {BoostPickup, 50}}},
+	"shielder": {0.4, []LootEntry{{ShieldPickup, 60}, {HealthPickup, 40}}},
+	"boss":     {1, []LootEntry{{WeaponCrate, 40}, {ShieldPickup, 30}, {HealthPickup, 30}}},
+}
+
+// rolls the loot table of archetype and drops a pickup at pos
+func DropLoot(archetype string, pos utils.Vec2) {
+	table, ok := lootTables[archetype]
+	if !ok || rand.Float32() >= table.DropChance {
+		return
+	}
+	total := float32(0)
+	for _, e := range table.Entries {
+		total += e.Weight
+	}
+	n := rand.Float32() * total
+	for _, e := range table.Entries {
+		if n < e.Weight {
+			NewPickup(e.Kind, pos)
+			return
+		}
+		n -= e.Weight
+	}
+}
+
+type Pickup struct {
+	DynamicEntity
+	Kind     PickupKind
+	lifeTime utils.Timer
+}
+
+func NewPickup(kind PickupKind, pos utils.Vec2) *Pickup {
+	p := &Pickup{DynamicEntity: DynamicEntity{pos, utils.Vec2{X: 0, Y: 0}, 0, "pickup", pickupColors[kind], false},
+		Kind:     kind,
+		lifeTime: utils.NewTimer(PICKUP_LIFETIME),
+	}
+	p.sparkle(particles.Outward, 8)
+	game.AddEntity(p)
+	return p
+}
+func (p Pickup) Type() string {
+	return p.etype
+}
+func (p Pickup) IsDestroyed() bool {
+	return p.Destroyed
+}
+func (p Pickup) rect() utils.Rect {
+	return utils.NewRect(int(p.Pos.X), int(p.Pos.Y), PICKUP_SIZE, PICKUP_SIZE)
+}
+func (p *Pickup) Update() {
+	player := nearestPlayer(p.Pos)
+	p.lifeTime.UpdateTimer()
+	if p.lifeTime.Ticked() {
+		p.Destroyed = true
+		return
+	}
+	toPlayer := utils.Vec2{X: player.Pos.X + PLAYER_RECT_SIZE/2 - p.Pos.X, Y: player.Pos.Y + PLAYER_RECT_SIZE/2 - p.Pos.Y}
+	if dist := toPlayer.Length(); dist < PICKUP_ATTRACT {
+		//gets faster the closer it is
+		p.speed = PICKUP_SPEED * (1 - dist/PICKUP_ATTRACT)
+		p.Dir = toPlayer
+		p.Dir.NormalizeDir()
+		p.Pos.X += p.Dir.X * p.speed
+		p.Pos.Y += p.Dir.Y * p.speed
+	}
+	for _, player := range game.livingPlayers() {
+		if p.rect().Collide(player.rect()) {
+			p.apply(player)
+			p.Destroyed = true
+			p.sparkle(particles.Inward, 12)
+			return
+		}
+	}
+}
+
+// gives the effect of the pickup to the player
+func (p Pickup) apply(player *Player) {
+	switch p.Kind {
+	case HealthPickup:
+		player.hp += 25
+	case ManaPickup:
+		player.mana += 35
+	case BoostPickup:
+		player.boosted = true
+		player.boost = utils.NewTimer(BOOST_TIME)
+	case ShieldPickup:
+		//barrier shield doesnt count towards the cap
+		player.shield = min(player.shield+1, MAX_SHIELD+player.barrierShield)
+	case WeaponCrate:
+		for _, w := range player.weapons {
+			if g, ok := w.(*Gun); ok && g.stats.MaxAmmo > 0 {
+				g.AddAmmo(g.stats.MaxAmmo / 2)
+			}
+		}
+	}
+}
+func (p Pickup) sparkle(motion particles.MotionType, amount uint) {
+	particlesSystem := particles.NewParticleSystem(
+		particles.WithArea(utils.NewRect(int(p.Pos.X)-8, int(p.Pos.Y)-8, PICKUP_SIZE+16, PICKUP_SIZE+16)),
+		particles.WithMotionType(motion),
+		particles.WithShrinking(0.1),
+		particles.WithModelParticle(*particles.NewParticle(particles.WithColor(p.color), particles.WithScale(3),
+			particles.WithSpeed(0.75))))
+	particlesSystem.Spawn(amount)
+	game.particles = append(game.particles, particlesSystem)
+}
+func (p Pickup) Draw(screen *ebiten.Image) {
+	//blink before despawning
+	if left := p.lifeTime.Time - p.lifeTime.GetCurrentTime(); left < PICKUP_BLINK && int(left*8)%2 == 0 {
+		return
+	}
+	game.cam.FillRect(screen, p.Pos.X, p.Pos.Y, PICKUP_SIZE, PICKUP_SIZE, p.color)
+	game.cam.StrokeRect(screen, p.Pos.X, p.Pos.Y, PICKUP_SIZE, PICKUP_SIZE, 1, color.White)
+}
@@ -6,105 +6,294 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hasona23/game/particles"
 	"github.com/hasona23/game/utils"
 )
 
 const (
-	PLAYER_RECT_SIZE = 16
-	ACCELRATION      = 0.75
-	PLAYER_FIRERATE  = 0.75
+	PLAYER_RECT_SIZE  = 16
+	ACCELRATION       = 0.75
+	PLAYER_FIRERATE   = 0.75
+	DASH_SPEED        = 6    //pixels per frame while dashing
+	DASH_TIME         = 0.15 //seconds
+	DASH_COOLDOWN     = 1
+	DASH_INVULNERABLE = 0.3 //seconds of invulnerability after starting a dash
+	HIT_TRAUMA        = 0.3 //camera shake when losing hp
 )
 
 type Player struct {
 	DynamicEntity
-	hp       int
-	mana     int
-	fireRate utils.Timer
+	hp            int
+	maxHp         int
+	mana          int
+	manaRegen     float32        //mana per second
+	manaAcc       float32        //regenerated mana not added yet
+	upgrades      map[string]int //times each upgrade was picked
+	vel           utils.Vec2     //how much the player actually moved last frame
+	weapons       []Weapon
+	weapon        int //index of weapon in hand
+	shield        int //hits absorbed before losing hp
+	barrierShield int //part of shield from the barrier ability . taken first and lost when barrier ends
+	barrier       utils.Timer
+	boost         utils.Timer
+	boosted       bool //weapons cool down twice as fast while boosted
+	dashing       bool
+	dashDir       utils.Vec2
+	dashTime      utils.Timer
+	dashCooldown  utils.Timer
+	invulnerable  utils.Timer //player cant take damage until it ends
+	abilities     []*Ability
+	hazard        utils.Timer //time until damaging tiles hurt again
+	controls      Controls
+	facing        utils.Vec2 //last direction moved in
+	index         int        //0 for the first player and 1 for the second
+	downed        bool       //out of hp in co-op and waiting to be revived
+	viewTick      uint32     //server tick an online player was shown when sending its input
 }
 
-var directions []utils.Vec2 = []utils.Vec2{{X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 0, Y: -1}, {X: -1, Y: -1}, {X: 1, Y: -1}, {X: -1, Y: 1}}
-
 // Update implements Entity.
 func NewPlayer(x, y float32) *Player {
-	return &Player{DynamicEntity: DynamicEntity{utils.Vec2{X: 4, Y: 4}, utils.Vec2{X: x, Y: y}, 1, "player", color.RGBA{128, 0, 129, 255}, false}, hp: HP, mana: 100, fireRate: utils.NewTimer(PLAYER_FIRERATE)}
+	return &Player{DynamicEntity: DynamicEntity{utils.Vec2{X: x, Y: y}, utils.Vec2{}, 1, "player", color.RGBA{128, 0, 129, 255}, false}, hp: HP, maxHp: HP, mana: 100,
+		upgrades:     make(map[string]int),
+		weapons:      DefaultWeapons(),
+		dashCooldown: utils.NewTimer(0),
+		invulnerable: utils.NewTimer(0),
+		barrier:      utils.NewTimer(0),
+		hazard:       utils.NewTimer(0),
+		abilities:    DefaultAbilities(),
+		controls:     FirstControls,
+		facing:       utils.Vec2{X: 1},
+	}
 
 }
+
+// in co-op players go down instead and the run ends when all are down
 func (p Player) IsDestroyed() bool {
-	return p.Destroyed || p.hp <= 0
+	return p.Destroyed || p.hp <= 0 && !game.coop
 }
 func (p Player) Type() string {
 	return p.etype
 }
 func (p Player) Draw(screen *ebiten.Image) {
-	//	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%v", p.hp), int(p.Pos.X)+int(game.cam.X)-2, int(p.Pos.Y)-12+int(game.cam.Y))
-	vector.DrawFilledRect(screen, p.Pos.X+game.cam.X, p.Pos.Y+game.cam.Y, PLAYER_RECT_SIZE, PLAYER_RECT_SIZE, p.color, false)
+	c := p.color
+	if p.IsInvulnerable() {
+		r, g, b, _ := p.color.RGBA()
+		c = color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 120}
+	}
+	if p.downed {
+		c = color.Gray{90}
+	}
+	game.cam.FillRect(screen, p.Pos.X, p.Pos.Y, PLAYER_RECT_SIZE, PLAYER_RECT_SIZE, c)
+	if p.shield > 0 {
+		game.cam.StrokeRect(screen, p.Pos.X-2, p.Pos.Y-2, PLAYER_RECT_SIZE+4, PLAYER_RECT_SIZE+4, float32(p.shield), ShieldColor)
+	}
 	// Draw nearby tile boundaries
 	/*for _, tile := range p.GetNearTiles() {
-		if tile.Variant == Rigid {
-			vector.DrawFilledRect(screen, tile.X+game.cam.X, tile.Y+game.cam.Y, TILE_SIZE, TILE_SIZE, color.RGBA{255, 0, 0, 100}, false)
+		if tile.Solid() {
+			game.cam.FillRect(screen, tile.X, tile.Y, TILE_SIZE, TILE_SIZE, color.RGBA{255, 0, 0, 100})
 		}
 	}*/
 }
+
+// cursor position in the world instead of the screen
+func cursorWorldPos() utils.Vec2 {
+	x, y := ebiten.CursorPosition()
+	return game.cam.ScreenToWorld(utils.Vec2{X: float32(x), Y: float32(y)})
+}
 func lerp(a, b, t float32) float32 {
 	return a + (b-a)*t
 }
+func (p *Player) CurrentWeapon() Weapon {
+	return p.weapons[p.weapon]
+}
+
+// switch weapons with number keys , mouse wheel or bumpers
+func (p *Player) switchWeapon() {
+	p.weapon = p.controls.SwitchWeapon(p.weapon, len(p.weapons))
+}
 func (p *Player) Update() {
-	p.fireRate.UpdateTimer()
-	p.mana = int(math.Min(math.Max(0, float64(p.mana)), 100))
-	//fmt.Println(p.fireRate.GetCurrentTime())
-	p.Dir.X = float32(math.Round(float64(lerp(p.Dir.X, 0, ACCELRATION))))
-	p.Dir.Y = float32(math.Round(float64(lerp(p.Dir.Y, 0, ACCELRATION))))
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		p.Dir.Y = float32(math.Round(float64(lerp(p.Dir.Y, -1, ACCELRATION))))
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) {
-		p.Dir.Y = float32(math.Round(float64(lerp(p.Dir.Y, 1, ACCELRATION))))
+	if p.hp <= 0 {
+		p.downed = true
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) {
-		p.Dir.X = float32(math.Round(float64(lerp(p.Dir.X, 1, ACCELRATION))))
+	if p.downed {
+		return
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		p.Dir.X = float32(math.Round(float64(lerp(p.Dir.X, -1, ACCELRATION))))
+	for _, w := range p.weapons {
+		w.Update()
+		if p.boosted {
+			w.Update()
+		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyQ) && p.mana >= 100 {
-
-		for _, dir := range directions {
-			NewBullet("player", p.Pos, dir, 2)
+	if p.boosted {
+		p.boost.UpdateTimer()
+		if p.boost.Ticked() {
+			p.boosted = false
 		}
-		p.mana = 0
-
+	}
+	p.hp = int(math.Min(float64(p.hp), float64(p.maxHp)))
+	p.manaAcc += p.manaRegen / float32(ebiten.TPS())
+	if p.manaAcc >= 1 {
+		p.mana += int(p.manaAcc)
+		p.manaAcc -= float32(int(p.manaAcc))
+	}
+	p.switchWeapon()
+	p.mana = int(math.Min(math.Max(0, float64(p.mana)), 100))
+	//fmt.Println(p.fireRate.GetCurrentTime())
+	mx, my := p.controls.Move()
+	p.Dir.X = float32(math.Round(float64(lerp(p.Dir.X, mx, ACCELRATION))))
+	p.Dir.Y = float32(math.Round(float64(lerp(p.Dir.Y, my, ACCELRATION))))
+	for i, a := range p.abilities {
+		a.Update(p, p.controls.AbilityPressed(i))
 	}
 
-	if (inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || ebiten.IsKeyPressed(ebiten.KeyE)) && p.fireRate.Ticked() {
-		x, y := ebiten.CursorPosition()
-		x -= int(game.cam.X)
-		y -= int(game.cam.Y)
-		NewBullet("player", p.Pos, utils.Vec2{X: float32(x) - (p.Pos.X), Y: float32(y) - (p.Pos.Y)}, 2)
+	if p.controls.FirePressed() {
+		c := p.aimPoint()
+		fired := len(game.entities["bullet"])
+		p.CurrentWeapon().Fire(p.Pos, math.Atan2(float64(c.Y-p.Pos.Y), float64(c.X-p.Pos.X)))
+		if game.server != nil && p.controls.Remote != nil {
+			game.server.rewindShots(game.entities["bullet"][fired:], p.viewTick)
+		}
 	}
 	p.Dir.NormalizeDir()
-	dx := int(math.Round(float64(p.Dir.X * p.speed)))
-	p.horizontalCollision(dx)
-	dy := int(math.Round(float64(p.Dir.Y * p.speed)))
-	p.verticalCollision(dy)
+	if p.Dir.X != 0 || p.Dir.Y != 0 {
+		p.facing = p.Dir
+	}
+	prevPos := p.Pos
+	p.updateBarrier()
+	p.updateDash()
+	if p.dashing {
+		p.dashMove()
+	} else {
+		speed := p.speed * speedAt(p.centre())
+		dx := int(math.Round(float64(p.Dir.X * speed)))
+		p.horizontalCollision(dx)
+		dy := int(math.Round(float64(p.Dir.Y * speed)))
+		p.verticalCollision(dy)
+	}
 	p.constraintMovemnt()
+	p.leash(prevPos)
+	p.reviveOthers()
+	p.vel = utils.Vec2{X: p.Pos.X - prevPos.X, Y: p.Pos.Y - prevPos.Y}
+	p.updateHazard()
 	// fmt.Printf("Velocity:%2v\n", p.Dir.X*p.speed)
 	for _, b := range game.entities["bullet"] {
-		if b.(*Bullet).rect().Collide(p.rect()) && b.(*Bullet).Shooter == "sniper" {
-			p.hp -= 30
+		if b.(*Bullet).rect().Collide(p.rect()) && b.(*Bullet).IsEnemy() {
+			p.TakeDamage(b.(*Bullet).damage)
 			b.(*Bullet).Destroyed = true
 			fmt.Println("I GOT SHOT")
 		}
 	}
 }
+
+// starts dash with space when cooldown is over and counts down dash timers
+func (p *Player) updateDash() {
+	if p.dashCooldown.GetCurrentTime() < p.dashCooldown.Time {
+		p.dashCooldown.UpdateTimer()
+	}
+	if p.invulnerable.GetCurrentTime() < p.invulnerable.Time {
+		p.invulnerable.UpdateTimer()
+	}
+	if p.dashing {
+		p.dashTime.UpdateTimer()
+		if p.dashTime.Ticked() {
+			p.dashing = false
+		}
+		return
+	}
+	if p.controls.DashPressed() && (p.Dir.X != 0 || p.Dir.Y != 0) &&
+		p.dashCooldown.GetCurrentTime() >= p.dashCooldown.Time {
+		p.dashing = true
+		p.dashDir = p.Dir
+		p.dashTime = utils.NewTimer(DASH_TIME)
+		p.dashCooldown = utils.NewTimer(DASH_COOLDOWN)
+		p.extendInvulnerable(DASH_INVULNERABLE)
+	}
+}
+
+// makes the player invulnerable for at least seconds without cutting a longer invulnerability short
+func (p *Player) extendInvulnerable(seconds float32) {
+	if p.invulnerable.Time-p.invulnerable.GetCurrentTime() < seconds {
+		p.invulnerable = utils.NewTimer(seconds)
+	}
+}
+
+// removes what is left of the barrier shield when it runs out
+func (p *Player) updateBarrier() {
+	if p.barrierShield == 0 {
+		return
+	}
+	p.barrier.UpdateTimer()
+	if p.barrier.Ticked() {
+		p.shield -= p.barrierShield
+		p.barrierShield = 0
+	}
+}
+
+// moves one pixel at a time so the dash cant go through rigid tiles
+func (p *Player) dashMove() {
+	dx := int(math.Round(float64(p.dashDir.X * DASH_SPEED)))
+	dy := int(math.Round(float64(p.dashDir.Y * DASH_SPEED)))
+	for range int(math.Abs(float64(dx))) {
+		p.horizontalCollision(int(math.Copysign(1, float64(dx))))
+	}
+	for range int(math.Abs(float64(dy))) {
+		p.verticalCollision(int(math.Copysign(1, float64(dy))))
+	}
+	//afterimage left behind
+	particlesSystem := particles.NewParticleSystem(
+		particles.WithArea(utils.NewRect(int(p.Pos.X), int(p.Pos.Y), 1, 1)),
+		particles.WithMotionType(particles.SingleDirection),
+		particles.WithShrinking(1),
+		particles.WithModelParticle(*particles.NewParticle(particles.WithColor(color.RGBA{128, 0, 129, 100}), particles.WithScale(PLAYER_RECT_SIZE),
+			particles.WithVelocity(utils.Vec2{X: 0, Y: 0}, 1))))
+	particlesSystem.Spawn(1)
+	game.particles = append(game.particles, particlesSystem)
+}
+
+// hurts the player every HAZARD_TICK while standing on a damaging tile
+func (p *Player) updateHazard() {
+	tile := game.Tilemap.GetTile(p.centre())
+	if tile == nil || tile.Props().Damage == 0 {
+		p.hazard = utils.NewTimer(0)
+		return
+	}
+	if p.hazard.GetCurrentTime() < p.hazard.Time {
+		p.hazard.UpdateTimer()
+		return
+	}
+	p.TakeDamage(tile.Props().Damage)
+	p.hazard = utils.NewTimer(HAZARD_TICK)
+}
+func (p Player) centre() utils.Vec2 {
+	return utils.Vec2{X: p.Pos.X + PLAYER_RECT_SIZE/2, Y: p.Pos.Y + PLAYER_RECT_SIZE/2}
+}
+func (p Player) IsInvulnerable() bool {
+	return p.invulnerable.GetCurrentTime() < p.invulnerable.Time
+}
+
+// shield absorbs hits before hp
+func (p *Player) TakeDamage(n int) {
+	if p.IsInvulnerable() {
+		return
+	}
+	if p.shield > 0 {
+		p.shield--
+		p.barrierShield = max(0, p.barrierShield-1)
+		return
+	}
+	p.hp -= n
+	if game.oneHitKills {
+		p.hp = 0
+	}
+	game.cam.Shake(HIT_TRAUMA)
+}
 func (p *Player) horizontalCollision(dx int) {
 	collisions := map[string]bool{"right": false, "left": false}
 
 	rect := p.rect()
 	rect.X += dx
 	for _, tile := range p.GetNearTiles() {
-		if tile.Variant == Rigid {
+		if tile.Solid() {
 
 			tRect := utils.NewRect(int(tile.X), int(tile.Y), TILE_SIZE, TILE_SIZE)
 			if rect.Collide(tRect) {
@@ -129,7 +318,7 @@ func (p *Player) verticalCollision(dy int) {
 	rect := p.rect()
 	rect.Y += dy
 	for _, tile := range p.GetNearTiles() {
-		if tile.Variant == Rigid {
+		if tile.Solid() {
 
 			tRect := utils.NewRect(int(tile.X), int(tile.Y), TILE_SIZE, TILE_SIZE)
 			if rect.Collide(tRect) {
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hasona23/game/ui"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	REVIVE_HP = 0.5 //share of max hp a revived player gets back
+	LEASH     = 40  //players can be this close to the screen edge when apart so the camera frames both
+)
+
+var SecondPlayerColor = color.RGBA{0, 150, 150, 255}
+
+// second player for local co-op
+func NewSecondPlayer(x, y float32) *Player {
+	p := NewPlayer(x, y)
+	p.color = SecondPlayerColor
+	p.controls = secondPlayerControls()
+	p.index = 1
+	return p
+}
+
+// all players including downed ones
+func (g *Game) players() []*Player {
+	players := make([]*Player, 0, len(g.entities["player"]))
+	for _, e := range g.entities["player"] {
+		players = append(players, e.(*Player))
+	}
+	return players
+}
+
+// players that are not down
+func (g *Game) livingPlayers() []*Player {
+	living := []*Player{}
+	for _, p := range g.players() {
+		if !p.downed {
+			living = append(living, p)
+		}
+	}
+	return living
+}
+
+// true when every player is down so the run is over
+func (g *Game) allPlayersDown() bool {
+	return len(g.livingPlayers()) == 0
+}
+
+// living player closest to pos . enemies chase and reward this player
+// returns the first player if all are down
+func nearestPlayer(pos utils.Vec2) *Player {
+	players := game.livingPlayers()
+	if len(players) == 0 {
+		return game.players()[0]
+	}
+	nearest := players[0]
+	for _, p := range players[1:] {
+		if distance(p.centre(), pos) < distance(nearest.centre(), pos) {
+			nearest = p
+		}
+	}
+	return nearest
+}
+func distance(a, b utils.Vec2) float32 {
+	return utils.Vec2{X: a.X - b.X, Y: a.Y - b.Y}.Length()
+}
+
+// middle of the living players for the camera to follow
+func (g *Game) playersCentre() utils.Vec2 {
+	players := g.livingPlayers()
+	if len(players) == 0 {
+		players = g.players()
+	}
+	c := utils.Vec2{}
+	for _, p := range players {
+		c.X += p.Pos.X / float32(len(players))
+		c.Y += p.Pos.Y / float32(len(players))
+	}
+	return c
+}
+
+// downed players touched by p get back up
+func (p *Player) reviveOthers() {
+	for _, other := range game.players() {
+		if other != p && other.downed && p.rect().Collide(other.rect()) {
+			other.downed = false
+			other.hp = int(float32(other.maxHp) * REVIVE_HP)
+			other.extendInvulnerable(DASH_INVULNERABLE * 3)
+		}
+	}
+}
+
+// undoes movement that takes p too far from the other living players for the camera to frame everyone
+// online players each have their own camera so they arent leashed
+func (p *Player) leash(prev utils.Vec2) {
+	if game.server != nil {
+		return
+	}
+	maxX, maxY := float32(320-PLAYER_RECT_SIZE-LEASH), float32(240-PLAYER_RECT_SIZE-LEASH)
+	for _, other := range game.livingPlayers() {
+		if other == p {
+			continue
+		}
+		if abs32(p.Pos.X-other.Pos.X) > maxX && abs32(p.Pos.X-other.Pos.X) > abs32(prev.X-other.Pos.X) {
+			p.Pos.X = prev.X
+		}
+		if abs32(p.Pos.Y-other.Pos.Y) > maxY && abs32(p.Pos.Y-other.Pos.Y) > abs32(prev.Y-other.Pos.Y) {
+			p.Pos.Y = prev.Y
+		}
+	}
+}
+func abs32(n float32) float32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// suffix of hud element names so each player has their own
+func (p *Player) hudSuffix() string {
+	if p.index == 0 {
+		return ""
+	}
+	return fmt.Sprint(p.index + 1)
+}
+
+// hp and mana bars , ability rings and weapon label . first player top left and second bottom right
+func addPlayerHUD(layout *ui.UILayout, p *Player, font []byte) {
+	s := p.hudSuffix()
+	x, y := 5, 5
+	ringX, ringY := 112, 20
+	weaponY := 220
+	if p.index > 0 {
+		x, y = 215, 199
+		ringX, ringY = 220, 190
+		weaponY = 220
+	}
+	layout.AddBar("hp"+s, ui.NewBar(x, y, p.hp, 8, utils.Point{X: 1, Y: 1}, color.RGBA{255, 0, 100, 255}, color.Gray{123}))
+	layout.AddBar("mana"+s, ui.NewBar(x, y+11, p.mana, 8, utils.Point{X: 1, Y: 1}, color.RGBA{100, 0, 255, 255}, color.Gray{123}))
+	for i, a := range p.abilities {
+		layout.AddRing(a.Name+s, ui.NewRing(float32(ringX+i*14), float32(ringY), 5, 2, a.Color, color.Gray{60}))
+	}
+	layout.AddLabel("weapon"+s, ui.NewLabel("", float32(x), float32(weaponY), font, 16, color.RGBA{0, 100, 255, 255}))
+}
+func updatePlayerHUD(layout *ui.UILayout, p *Player) {
+	s := p.hudSuffix()
+	bar, _ := layout.GetBar("hp" + s)
+	bar.SetValueAndMax(p.maxHp, p.hp)
+	mbar, _ := layout.GetBar("mana" + s)
+	mbar.SetValue(p.mana)
+	for _, a := range p.abilities {
+		ring, _ := layout.GetRing(a.Name + s)
+		ring.SetRatio(a.Ready())
+		//gray when not enough mana
+		if p.mana >= a.ManaCost {
+			ring.SetRingColor(a.Color)
+		} else {
+			ring.SetRingColor(color.Gray{90})
+		}
+	}
+	label, _ := layout.GetLabel("weapon" + s)
+	label.SetText(p.CurrentWeapon().Status())
+	if p.downed {
+		label.SetText("DOWN . touch to revive")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/utils"
+)
+
+// tiles of a CHUNK_SIZE block drawn once to an image and redrawn only when they change
+type tileCache struct {
+	img   *ebiten.Image
+	dirty []*Tile
+}
+
+// step between tiles in pixels
+const TILE_STEP = TILE_SIZE + SPACING
+
+// queues tile and its neighbours to be redrawn in their cached blocks
+// neighbours are redrawn too as their autotile sprites depend on the tile
+func (t *Tilemap) markDirty(tile *Tile) {
+	if t == nil || t.cache == nil {
+		return
+	}
+	tx, ty := int(tile.X)/TILE_STEP, int(tile.Y)/TILE_STEP
+	for y := ty - 1; y <= ty+1; y++ {
+		for x := tx - 1; x <= tx+1; x++ {
+			n := t.peekTile(x, y)
+			if n == nil {
+				continue
+			}
+			if c, ok := t.cache[utils.Point{X: x / CHUNK_SIZE, Y: y / CHUNK_SIZE}]; ok {
+				c.dirty = append(c.dirty, n)
+			}
+		}
+	}
+}
+
+// draws only the blocks and tiles inside the view of the camera
+// static tiles come from the cache and animated ones are drawn on top every frame
+func (t *Tilemap) Draw(screen *ebiten.Image) {
+	if t.cache == nil {
+		t.cache = make(map[utils.Point]*tileCache)
+	}
+	view := game.cam.ViewRect()
+	camGeoM := game.cam.GeoM()
+	x0, y0 := max(0, view.Left()/TILE_STEP), max(0, view.Top()/TILE_STEP)
+	x1, y1 := min(t.Width-1, view.Right()/TILE_STEP), min(t.Height-1, view.Bottom()/TILE_STEP)
+	visible := map[utils.Point]bool{}
+	for cy := y0 / CHUNK_SIZE; cy <= y1/CHUNK_SIZE; cy++ {
+		for cx := x0 / CHUNK_SIZE; cx <= x1/CHUNK_SIZE; cx++ {
+			key := utils.Point{X: cx, Y: cy}
+			c := t.blockCache(key)
+			if c == nil {
+				continue
+			}
+			visible[key] = true
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(cx*CHUNK_SIZE*TILE_STEP), float64(cy*CHUNK_SIZE*TILE_STEP))
+			op.GeoM.Concat(camGeoM)
+			screen.DrawImage(c.img, op)
+		}
+	}
+	//blocks out of view are dropped so big maps dont keep every image
+	for key := range t.cache {
+		if !visible[key] {
+			t.dropCache(key)
+		}
+	}
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			tile := t.peekTile(x, y)
+			if tile == nil {
+				continue
+			}
+			if tile.transitioning(t.Terrain.Transition) {
+				tile.drawTransition(screen, camGeoM, t.Terrain.Transition)
+			} else if t.spriteOf(tile) == nil && tile.Props().Style == GlowStyle {
+				tile.drawStyled(screen, camGeoM, tile.X, tile.Y)
+			}
+		}
+	}
+}
+
+// returns the cache of the block at key rendering it first if needed
+// returns nil if the block is an unloaded chunk
+func (t *Tilemap) blockCache(key utils.Point) *tileCache {
+	if c, ok := t.cache[key]; ok {
+		for _, tile := range c.dirty {
+			t.renderTile(c, key, tile)
+		}
+		c.dirty = c.dirty[:0]
+		return c
+	}
+	if t.chunked {
+		if _, ok := t.chunks[key]; !ok {
+			return nil
+		}
+	}
+	c := &tileCache{img: ebiten.NewImage(CHUNK_SIZE*TILE_STEP, CHUNK_SIZE*TILE_STEP)}
+	for y := key.Y * CHUNK_SIZE; y < min(t.Height, (key.Y+1)*CHUNK_SIZE); y++ {
+		for x := key.X * CHUNK_SIZE; x < min(t.Width, (key.X+1)*CHUNK_SIZE); x++ {
+			t.renderTile(c, key, t.TileAt(x, y))
+		}
+	}
+	t.cache[key] = c
+	return c
+}
+
+// draws the current state of tile in the cached image of block key
+func (t *Tilemap) renderTile(c *tileCache, key utils.Point, tile *Tile) {
+	x := tile.X - float32(key.X*CHUNK_SIZE*TILE_STEP)
+	y := tile.Y - float32(key.Y*CHUNK_SIZE*TILE_STEP)
+	c.img.SubImage(image.Rect(int(x), int(y), int(x)+TILE_SIZE, int(y)+TILE_SIZE)).(*ebiten.Image).Clear()
+	sprite := t.spriteOf(tile)
+	if a, ok := autotiles[tile.Variant]; ok && sprite == nil {
+		tx, ty := int(tile.X)/TILE_STEP, int(tile.Y)/TILE_STEP
+		sprite = a.Frame(t, tx, ty).GetImg()
+	}
+	if sprite == nil {
+		tile.drawStyled(c.img, ebiten.GeoM{}, x, y)
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(TILE_SIZE/float64(sprite.Bounds().Dx()), TILE_SIZE/float64(sprite.Bounds().Dy()))
+	op.GeoM.Translate(float64(x), float64(y))
+	c.img.DrawImage(sprite, op)
+}
+
+// sprite drawn for the tile . nil means it is drawn with its variant colors
+func (t *Tilemap) spriteOf(tile *Tile) *ebiten.Image {
+	if tile.Variant != tile.spriteVariant {
+		return t.variantSprites[tile.Variant]
+	}
+	return tile.Sprite
+}
+
+// frees the image of the block at key
+func (t *Tilemap) dropCache(key utils.Point) {
+	if c, ok := t.cache[key]; ok {
+		c.img.Deallocate()
+		delete(t.cache, key)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+const (
+	SAMPLE_RATE = 44100
+	NOTE_LENGTH = 0.15 //seconds
+)
+
+var audioContext *audio.Context
+var musicPlayer *audio.Player
+
+// only one audio context can exist so its created the first time its needed
+func getAudioContext() *audio.Context {
+	if audioContext == nil {
+		audioContext = audio.NewContext(SAMPLE_RATE)
+	}
+	return audioContext
+}
+
+// generates a short rising arpeggio and plays it
+func playFanfare() {
+	//headless server has nobody to hear it
+	if game.server != nil {
+		return
+	}
+	notes := []float64{523.25, 659.25, 783.99, 1046.5} //C E G C
+	samplesPerNote := int(SAMPLE_RATE * NOTE_LENGTH)
+	//16 bit stereo little endian
+	buf := make([]byte, 0, len(notes)*samplesPerNote*4)
+	for i, freq := range notes {
+		length := samplesPerNote
+		if i == len(notes)-1 {
+			length *= 3 //hold the last note
+		}
+		for j := range length {
+			fade := 1 - float64(j)/float64(length)
+			v := int16(math.Sin(2*math.Pi*freq*float64(j)/SAMPLE_RATE) * fade * 0.3 * math.MaxInt16)
+			buf = append(buf, byte(v), byte(v>>8), byte(v), byte(v>>8))
+		}
+	}
+	getAudioContext().NewPlayerFromBytes(buf).Play()
+}
+
+// plays music file in a loop replacing the current music
+// supports ogg, mp3 and wav . empty path just stops the music
+func playMusic(path string) error {
+	stopMusic()
+	if path == "" || game.server != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var stream interface {
+		io.ReadSeeker
+		Length() int64
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ogg":
+		stream, err = vorbis.DecodeWithSampleRate(SAMPLE_RATE, bytes.NewReader(data))
+	case ".mp3":
+		stream, err = mp3.DecodeWithSampleRate(SAMPLE_RATE, bytes.NewReader(data))
+	case ".wav":
+		stream, err = wav.DecodeWithSampleRate(SAMPLE_RATE, bytes.NewReader(data))
+	default:
+		return fmt.Errorf("music %v: unsupported format", path)
+	}
+	if err != nil {
+		return fmt.Errorf("music %v: %w", path, err)
+	}
+	musicPlayer, err = getAudioContext().NewPlayer(audio.NewInfiniteLoop(stream, stream.Length()))
+	if err != nil {
+		return fmt.Errorf("music %v: %w", path, err)
+	}
+	musicPlayer.Play()
+	return nil
+}
+func stopMusic() {
+	if musicPlayer != nil {
+		musicPlayer.Close()
+		musicPlayer = nil
+	}
+}
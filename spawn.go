@@ -0,0 +1,120 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/hasona23/game/level"
+	"github.com/hasona23/game/particles"
+	"github.com/hasona23/game/utils"
+)
+
+const (
+	WAVE_SPAWNS = 8   //enemies per wave
+	BOSS_WAVE   = 5   //boss appears every BOSS_WAVE waves
+	SPAWN_RANGE = 320 //distance from player enemies spawn in on chunked maps
+)
+
+// an enemy that can be spawned and how likely it is compared to others
+type SpawnEntry struct {
+	Name   string
+	Weight float32
+	Spawn  func(pos utils.Vec2)
+}
+
+// names are the ones levels can use . they are added to level.Enemies in init
+var spawnTable = []SpawnEntry{
+	{"bomber", 50, func(pos utils.Vec2) { NewBomber(pos) }},
+	{"sniper", 20, func(pos utils.Vec2) { NewSniper(pos) }},
+	{"digger", 10, func(pos utils.Vec2) { NewDigger(pos) }},
+	{"splitter", 10, func(pos utils.Vec2) { NewSplitter(pos) }},
+	{"shielder", 10, func(pos utils.Vec2) { NewShielder(pos) }},
+}
+
+func init() {
+	for _, e := range spawnTable {
+		level.Enemies = append(level.Enemies, e.Name)
+	}
+}
+
+// spawns a random enemy from the spawn table based on weights
+// if allowed isnt empty only enemies with names in it can spawn
+func SpawnEnemy(pos utils.Vec2, allowed []string) {
+	table := spawnTable
+	if len(allowed) > 0 {
+		table = slices.DeleteFunc(slices.Clone(spawnTable), func(e SpawnEntry) bool { return !slices.Contains(allowed, e.Name) })
+	}
+	total := float32(0)
+	for _, e := range table {
+		total += e.Weight
+	}
+	n := rand.Float32() * total
+	for _, e := range table {
+		if n < e.Weight {
+			e.Spawn(pos)
+			return
+		}
+		n -= e.Weight
+	}
+}
+
+// starts spawning the next enemy and moves to next wave when the wave is done
+// every BOSS_WAVE waves a boss is spawned instead and spawning stops until its dead
+func (g *Game) spawnNext() {
+	x, y := g.spawnPos()
+	var allowed []string
+	if pos, enemies, ok := g.levelSpawn(); ok {
+		x, y = pos.X, pos.Y
+		allowed = enemies
+	}
+	if len(allowed) == 0 {
+		allowed = g.mode.Spawns().Enemies
+	}
+	if g.waveSpawns >= WAVE_SPAWNS {
+		g.nextWave()
+		if g.wave%BOSS_WAVE == 0 && g.mode.Spawns().BossWaves {
+			g.bossFight = true
+			g.showBanner("BOSS INCOMING", 2)
+			g.particles = append(g.particles, newSpawnPortal(x, y, BOSS_SIZE*2, "bossspawn"))
+			return
+		}
+	}
+	g.waveSpawns++
+	portal := newSpawnPortal(x, y, 32, "spawn")
+	g.portals[portal] = allowed
+	g.particles = append(g.particles, portal)
+}
+
+// moves to the next wave and offers upgrades for surviving the last one
+func (g *Game) nextWave() {
+	g.wave++
+	g.waveSpawns = 0
+	g.offerUpgrades()
+}
+
+// group of red particles that releases an enemy when it ends
+func newSpawnPortal(x, y float32, size int, name string) *particles.ParticleSystem {
+	particlesSystem := particles.NewParticleSystem(
+		particles.WithArea(utils.NewRect(int(x), int(y), size, size)),
+		particles.WithName(name),
+		particles.WithMotionType(particles.Circular),
+		particles.WithShrinking(0.2*32/float32(size)),
+		particles.WithModelParticle(*particles.NewParticle(particles.WithColor(color.RGBA{255, 0, 0, 255}), particles.WithScale(16),
+			particles.WithSpeed(0.5))))
+	particlesSystem.Spawn(uint(10 * size / 32))
+	return particlesSystem
+}
+
+// random position for enemies to spawn
+// on chunked maps enemies spawn around the players instead of the whole map
+func (g *Game) spawnPos() (float32, float32) {
+	if !g.Tilemap.chunked {
+		return rand.Float32() * float32(g.Tilemap.GetWidth()), rand.Float32() * float32(g.Tilemap.GetHieght())
+	}
+	centre := g.playersCentre()
+	x := centre.X + (rand.Float32()*2-1)*SPAWN_RANGE
+	y := centre.Y + (rand.Float32()*2-1)*SPAWN_RANGE
+	return float32(math.Max(0, math.Min(float64(x), float64(g.Tilemap.GetWidth())))), float32(math.Max(0, math.Min(float64(y), float64(g.Tilemap.GetHieght()))))
+}
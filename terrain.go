@@ -0,0 +1,131 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/level"
+	"github.com/hasona23/game/utils"
+)
+
+const TERRAIN_TICK = 0.25 //seconds between terrain simulation steps
+
+// rules used for generated maps . levels bring their own
+var DefaultTerrain = level.Terrain{RegrowDelay: 25, CrumbleDelay: 4, CrumbleNeighbours: 1, Transition: 0.3}
+
+// runs regrowth and crumbling every TERRAIN_TICK . does nothing without rules
+func (t *Tilemap) UpdateTerrain() {
+	if t.Terrain == (level.Terrain{}) {
+		return
+	}
+	if t.terrainTick.Time == 0 {
+		t.terrainTick = utils.NewTimer(TERRAIN_TICK)
+	}
+	t.terrainTick.UpdateTimer()
+	if !t.terrainTick.Ticked() {
+		return
+	}
+	t.forEachLoaded(func(x, y int, tile *Tile) {
+		switch {
+		case t.Terrain.RegrowDelay > 0 && tile.regrows && !tile.Solid():
+			t.regrow(x, y, tile)
+		case t.Terrain.CrumbleDelay > 0 && tile.Solid() && tile.Props().Diggable:
+			t.crumble(x, y, tile)
+		}
+	})
+}
+
+// dug tiles grow back after the delay if they touch solid ground and nothing stands on them
+func (t *Tilemap) regrow(x, y int, tile *Tile) {
+	if secondsSince(tile.changedAt) < t.Terrain.RegrowDelay || t.solidNeighbours(x, y) == 0 || occupied(tile.rect()) {
+		return
+	}
+	tile.SetVariant(tile.regrowAs)
+}
+
+// solid tiles with too few solid neighbours crumble to air after the delay
+func (t *Tilemap) crumble(x, y int, tile *Tile) {
+	if t.solidNeighbours(x, y) >= t.Terrain.CrumbleNeighbours {
+		tile.isolated = false
+		return
+	}
+	if !tile.isolated {
+		tile.isolated = true
+		tile.isolatedAt = game.frame
+		return
+	}
+	if secondsSince(tile.isolatedAt) >= t.Terrain.CrumbleDelay {
+		tile.SetVariant(Air)
+	}
+}
+
+// counts solid tiles around column x and row y . unloaded chunks count as air
+func (t *Tilemap) solidNeighbours(x, y int) int {
+	n := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			if tile := t.peekTile(x+dx, y+dy); tile != nil && tile.Solid() {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// like TileAt but returns nil instead of generating missing chunks
+func (t *Tilemap) peekTile(x, y int) *Tile {
+	if !t.chunked {
+		return t.TileAt(x, y)
+	}
+	if x < 0 || y < 0 || x >= t.Width || y >= t.Height {
+		return nil
+	}
+	if _, ok := t.chunks[utils.Point{X: x / CHUNK_SIZE, Y: y / CHUNK_SIZE}]; !ok {
+		return nil
+	}
+	return t.TileAt(x, y)
+}
+
+// calls fn with the column and row of every tile in memory
+func (t *Tilemap) forEachLoaded(fn func(x, y int, tile *Tile)) {
+	t.ForEachTile(func(tile *Tile) {
+		fn(int(tile.X)/(TILE_SIZE+SPACING), int(tile.Y)/(TILE_SIZE+SPACING), tile)
+	})
+}
+
+// true if an entity other than a bullet overlaps r
+func occupied(r utils.Rect) bool {
+	for etype, entities := range game.entities {
+		if etype == "bullet" {
+			continue
+		}
+		for _, e := range entities {
+			if re, ok := e.(interface{ rect() utils.Rect }); ok && re.rect().Collide(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+func secondsSince(frame int) float32 {
+	return float32(game.frame-frame) / float32(ebiten.TPS())
+}
+
+// true while the last change of the tile is animating
+func (tile Tile) transitioning(transition float32) bool {
+	return transition > 0 && secondsSince(tile.changedAt) < transition
+}
+
+// draws the new variant growing from the centre over the old one through geoM
+func (tile Tile) drawTransition(dst *ebiten.Image, geoM ebiten.GeoM, transition float32) {
+	progress := secondsSince(tile.changedAt) / transition
+	x, y := tile.X, tile.Y
+	utils.FillRect(dst, geoM, x, y, TILE_SIZE, TILE_SIZE, tile.prev.Props().Color)
+	size := TILE_SIZE * progress
+	c := color.NRGBAModel.Convert(tile.Props().Color).(color.NRGBA)
+	c.A = uint8(155 + 100*progress)
+	utils.FillRect(dst, geoM, x+(TILE_SIZE-size)/2, y+(TILE_SIZE-size)/2, size, size, c)
+}
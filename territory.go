@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hasona23/game/ui"
+	"github.com/hasona23/game/utils"
+)
+
+// territory mode . hold open ground against bombers filling the map back
+const (
+	TERRITORY_TARGET = 0.6  //share of open tiles the player has to hold
+	TERRITORY_HOLD   = 30   //seconds the target has to be held to win
+	TERRITORY_LOSE   = 0.15 //player loses if open tiles fall to this share
+	TERRITORY_SPAWN  = 1.5  //seconds between enemy spawns
+)
+
+type Territory struct {
+	open  float32     //share of tiles that are not solid
+	rigid float32     //share of tiles that are rigid . the ones enemies fill back
+	held  utils.Timer //how long the target has been held in a row
+}
+
+func NewTerritory() Mode { return &Territory{held: utils.NewTimer(TERRITORY_HOLD)} }
+func (Territory) Name() string {
+	return "Territory"
+}
+func (Territory) Description() string {
+	return fmt.Sprintf("hold %v%% open ground for %v seconds", int(TERRITORY_TARGET*100), TERRITORY_HOLD)
+}
+
+// enemies that fill tiles are the threat so they spawn the most
+func (Territory) Spawns() SpawnConfig {
+	return SpawnConfig{Interval: TERRITORY_SPAWN, Enemies: []string{"bomber", "splitter", "shielder"}}
+}
+func (t *Territory) Start(g *Game) {
+	hud := g.ui[States.Main]
+	hud.AddLabel("territory", ui.NewLabel("", 200, 24, g.font, 16, color.RGBA{0, 100, 255, 255}))
+	hud.AddBar("hold", ui.NewBar(200, 40, 100, 6, utils.Point{X: 1, Y: 1}, color.RGBA{255, 215, 0, 255}, color.Gray{123}))
+	g.showBanner(fmt.Sprintf("HOLD %v%% OPEN GROUND", int(TERRITORY_TARGET*100)), 2)
+}
+
+// counts open tiles , updates the hud and checks win and lose
+func (t *Territory) Update(g *Game) ModeResult {
+	open, rigid, total := 0, 0, 0
+	g.Tilemap.ForEachTile(func(tile *Tile) {
+		total++
+		if !tile.Solid() {
+			open++
+		}
+		if tile.Variant == Rigid {
+			rigid++
+		}
+	})
+	t.open = float32(open) / float32(max(total, 1))
+	t.rigid = float32(rigid) / float32(max(total, 1))
+	if t.open >= TERRITORY_TARGET {
+		t.held.UpdateTimer()
+	} else {
+		t.held.Reset()
+	}
+	label, _ := g.ui[States.Main].GetLabel("territory")
+	label.SetText(fmt.Sprintf("open %v%% rigid %v%%", int(t.open*100), int(t.rigid*100)))
+	bar, _ := g.ui[States.Main].GetBar("hold")
+	bar.SetValue(int(t.held.GetCurrentTime() / TERRITORY_HOLD * 100))
+	if t.held.GetCurrentTime() >= TERRITORY_HOLD {
+		g.showBanner("TERRITORY HELD", 3)
+		return Won
+	}
+	//map refilled so the run is over
+	if t.open <= TERRITORY_LOSE {
+		g.showBanner("MAP REFILLED", 3)
+		return Lost
+	}
+	return Playing
+}
@@ -0,0 +1,188 @@
+package tiled
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// true when the json file at path is a Tiled map and not a level of the game
+// Tiled exports maps as .json too so the keys decide . every Tiled map has a tiledversion or layers
+func IsJSONMap(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return false
+	}
+	_, version := keys["tiledversion"]
+	_, layers := keys["layers"]
+	return version || layers
+}
+
+// layout of .tmj maps and .tsj tilesets
+type jsonMap struct {
+	Width       int            `json:"width"`
+	Height      int            `json:"height"`
+	TileWidth   int            `json:"tilewidth"`
+	TileHeight  int            `json:"tileheight"`
+	Infinite    bool           `json:"infinite"`
+	Orientation string         `json:"orientation"`
+	Properties  []jsonProperty `json:"properties"`
+	Tilesets    []jsonTileset  `json:"tilesets"`
+	Layers      []jsonLayer    `json:"layers"`
+}
+type jsonProperty struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+type jsonTileset struct {
+	FirstGID   int    `json:"firstgid"`
+	Source     string `json:"source"` //external tileset file
+	Image      string `json:"image"`
+	TileWidth  int    `json:"tilewidth"`
+	TileHeight int    `json:"tileheight"`
+	Columns    int    `json:"columns"`
+	TileCount  int    `json:"tilecount"`
+	Tiles      []struct {
+		ID         int            `json:"id"`
+		Properties []jsonProperty `json:"properties"`
+	} `json:"tiles"`
+}
+type jsonLayer struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data"` //array of gids or base64 string
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Objects     []jsonObject    `json:"objects"`
+	Layers      []jsonLayer     `json:"layers"` //children of group layers
+}
+type jsonObject struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Class      string         `json:"class"` //Tiled 1.9+ renamed type to class
+	X          float64        `json:"x"`
+	Y          float64        `json:"y"`
+	Width      float64        `json:"width"`
+	Height     float64        `json:"height"`
+	Point      bool           `json:"point"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+func readJSON(path string) (*tiledMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jm jsonMap
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return nil, err
+	}
+	m := &tiledMap{Width: jm.Width, Height: jm.Height, TileWidth: jm.TileWidth, TileHeight: jm.TileHeight,
+		Infinite: jm.Infinite, Orientation: jm.Orientation, Properties: jsonProperties(jm.Properties)}
+	for _, jts := range jm.Tilesets {
+		ts, err := readJSONTileset(jts, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+	if err := m.addJSONLayers(jm.Layers); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// group layers are flattened keeping their order
+func (m *tiledMap) addJSONLayers(layers []jsonLayer) error {
+	for _, jl := range layers {
+		switch jl.Type {
+		case "group":
+			if err := m.addJSONLayers(jl.Layers); err != nil {
+				return err
+			}
+		case "tilelayer":
+			gids, err := jsonLayerData(jl)
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", jl.Name, err)
+			}
+			m.Layers = append(m.Layers, layer{Name: jl.Name, Type: jl.Type, Data: gids})
+		case "objectgroup":
+			l := layer{Name: jl.Name, Type: jl.Type}
+			for _, o := range jl.Objects {
+				typ := o.Type
+				if typ == "" {
+					typ = o.Class
+				}
+				l.Objects = append(l.Objects, object{Name: o.Name, Type: typ, X: o.X, Y: o.Y, Width: o.Width, Height: o.Height,
+					Point: o.Point, Properties: jsonProperties(o.Properties)})
+			}
+			m.Layers = append(m.Layers, l)
+		}
+	}
+	return nil
+}
+
+func jsonLayerData(jl jsonLayer) ([]int, error) {
+	if jl.Encoding == "base64" {
+		var s string
+		if err := json.Unmarshal(jl.Data, &s); err != nil {
+			return nil, err
+		}
+		return decodeBase64(s, jl.Compression)
+	}
+	var gids []int
+	if err := json.Unmarshal(jl.Data, &gids); err != nil {
+		return nil, err
+	}
+	return gids, nil
+}
+
+// external tilesets are read from their .tsj file next to the map
+func readJSONTileset(jts jsonTileset, dir string) (tileset, error) {
+	firstGID := jts.FirstGID
+	if jts.Source != "" {
+		if filepath.Ext(jts.Source) == ".tsx" {
+			ts, err := readTSX(dir, jts.Source)
+			ts.FirstGID = firstGID
+			return ts, err
+		}
+		path := filepath.Join(dir, jts.Source)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return tileset{}, err
+		}
+		if err := json.Unmarshal(data, &jts); err != nil {
+			return tileset{}, fmt.Errorf("tileset %v: %w", path, err)
+		}
+		//image path is relative to the tileset file so make it relative to the map
+		if jts.Image != "" {
+			jts.Image = filepath.Join(filepath.Dir(jts.Source), jts.Image)
+		}
+	}
+	ts := tileset{FirstGID: firstGID, Image: jts.Image, TileWidth: jts.TileWidth, TileHeight: jts.TileHeight,
+		Columns: jts.Columns, Count: jts.TileCount}
+	for _, t := range jts.Tiles {
+		ts.Tiles = append(ts.Tiles, tilesetTile{ID: t.ID, Properties: jsonProperties(t.Properties)})
+	}
+	return ts, nil
+}
+
+func jsonProperties(props []jsonProperty) []property {
+	var res []property
+	for _, p := range props {
+		value := fmt.Sprint(p.Value)
+		//json numbers are float64 which Sprint prints as 1e+06 when big
+		if f, ok := p.Value.(float64); ok {
+			value = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		res = append(res, property{Name: p.Name, Type: p.Type, Value: value})
+	}
+	return res
+}
@@ -0,0 +1,310 @@
+// Package tiled imports maps made in the Tiled map editor (https://www.mapeditor.org)
+//
+// tiles get their variant from a "variant" custom property on the tileset tile.
+// objects are turned into level data by their type (class in newer Tiled versions):
+//
+//	player_start : point where the player starts
+//	spawn_zone   : area enemies spawn in . optional "enemies" property with comma separated names
+//	trigger      : area with "action" and "value" properties
+//	objective    : "kind" and "target" properties
+//
+// map properties "name" , "author" and "music" become the level metadata
+// "regrow_delay" , "crumble_delay" , "crumble_neighbours" and "transition" set the terrain rules
+package tiled
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hasona23/game/level"
+)
+
+// variant used for empty cells
+const EmptyVariant = "air"
+
+// properties allowed on each kind of thing . anything else is a validation error
+var (
+	mapProperties       = []string{"name", "author", "music", "regrow_delay", "crumble_delay", "crumble_neighbours", "transition"}
+	tileProperties      = []string{"variant"}
+	spawnZoneProperties = []string{"enemies"}
+	triggerProperties   = []string{"action", "value"}
+	objectiveProperties = []string{"kind", "target"}
+)
+
+// format independent map read from json or tmx
+type tiledMap struct {
+	Width, Height         int
+	TileWidth, TileHeight int
+	Infinite              bool
+	Orientation           string
+	Properties            []property
+	Tilesets              []tileset
+	Layers                []layer
+}
+type property struct {
+	Name  string
+	Type  string
+	Value string
+}
+type tileset struct {
+	FirstGID              int
+	Image                 string //relative to the map file
+	TileWidth, TileHeight int
+	Columns, Count        int
+	Tiles                 []tilesetTile
+}
+type tilesetTile struct {
+	ID         int
+	Properties []property
+}
+type layer struct {
+	Name    string
+	Type    string //tilelayer or objectgroup
+	Data    []int  //gids row by row
+	Objects []object
+}
+type object struct {
+	Name                string
+	Type                string
+	X, Y, Width, Height float64 //in pixels
+	Point               bool
+	Properties          []property
+}
+
+// flags Tiled stores in the high bits of gids
+const flipFlags = 0xF0000000
+
+// imports a Tiled map . format is picked from the extension (.tmx or .tmj/.json)
+func Load(path string) (*level.Level, error) {
+	var m *tiledMap
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tmx":
+		m, err = readTMX(path)
+	case ".tmj", ".json":
+		m, err = readJSON(path)
+	default:
+		return nil, fmt.Errorf("tiled %v: unsupported extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tiled %v: %w", path, err)
+	}
+	l, err := m.toLevel(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("tiled %v: %w", path, err)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("tiled %v: %w", path, err)
+	}
+	return l, nil
+}
+
+func checkProperties(what string, props []property, allowed []string) error {
+	for _, p := range props {
+		if !contains(allowed, p.Name) {
+			return fmt.Errorf("%v has unknown property %q (allowed: %v)", what, p.Name, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+func getProperty(props []property, name string) (string, bool) {
+	for _, p := range props {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *tiledMap) toLevel(dir string) (*level.Level, error) {
+	if m.Orientation != "" && m.Orientation != "orthogonal" {
+		return nil, fmt.Errorf("only orthogonal maps are supported got %q", m.Orientation)
+	}
+	if m.Infinite {
+		return nil, fmt.Errorf("infinite maps are not supported")
+	}
+	if m.TileWidth < 1 || m.TileHeight < 1 {
+		return nil, fmt.Errorf("map tile size %vx%v must be at least 1x1", m.TileWidth, m.TileHeight)
+	}
+	if err := checkProperties("map", m.Properties, mapProperties); err != nil {
+		return nil, err
+	}
+	l := &level.Level{Version: level.Version, Width: m.Width, Height: m.Height, Legend: map[string]string{},
+		VariantSprites: map[string]int{}}
+	l.Meta.Name, _ = getProperty(m.Properties, "name")
+	l.Meta.Author, _ = getProperty(m.Properties, "author")
+	if music, ok := getProperty(m.Properties, "music"); ok && music != "" {
+		l.Meta.Music = filepath.Join(dir, music)
+	}
+
+	if err := readTerrain(l, m.Properties); err != nil {
+		return nil, err
+	}
+
+	//variant of every gid that has one
+	variants := map[int]string{}
+	for _, ts := range m.Tilesets {
+		for _, t := range ts.Tiles {
+			if err := checkProperties(fmt.Sprintf("tile %v of tileset %v", t.ID, ts.Image), t.Properties, tileProperties); err != nil {
+				return nil, err
+			}
+			if v, ok := getProperty(t.Properties, "variant"); ok {
+				gid := ts.FirstGID + t.ID
+				variants[gid] = v
+				if _, ok := l.VariantSprites[v]; !ok {
+					l.VariantSprites[v] = gid
+				}
+			}
+		}
+		if ts.Image != "" {
+			l.Tilesets = append(l.Tilesets, level.Tileset{Image: filepath.Join(dir, ts.Image), FirstGID: ts.FirstGID, Count: ts.Count,
+				TileWidth: ts.TileWidth, TileHeight: ts.TileHeight, Columns: ts.Columns})
+		}
+	}
+
+	//later tile layers are drawn on top so they override earlier ones
+	gids := make([]int, m.Width*m.Height)
+	for _, ly := range m.Layers {
+		if ly.Type != "tilelayer" {
+			continue
+		}
+		if len(ly.Data) != len(gids) {
+			return nil, fmt.Errorf("layer %q has %v tiles but map has %v", ly.Name, len(ly.Data), len(gids))
+		}
+		for i, gid := range ly.Data {
+			if gid&flipFlags != 0 {
+				return nil, fmt.Errorf("layer %q has flipped tile at %v,%v which is not supported", ly.Name, i%m.Width, i/m.Width)
+			}
+			if gid != 0 {
+				gids[i] = gid
+			}
+		}
+	}
+	symbols := map[string]string{}
+	nextSymbol := func(variant string) string {
+		if s, ok := symbols[variant]; ok {
+			return s
+		}
+		s := string(rune('a' + len(symbols)))
+		symbols[variant] = s
+		l.Legend[s] = variant
+		return s
+	}
+	for y := range m.Height {
+		var row strings.Builder
+		for x := range m.Width {
+			gid := gids[y*m.Width+x]
+			variant := EmptyVariant
+			if gid != 0 {
+				v, ok := variants[gid]
+				if !ok {
+					return nil, fmt.Errorf("tile at %v,%v uses gid %v which has no variant property", x, y, gid)
+				}
+				variant = v
+			}
+			row.WriteString(nextSymbol(variant))
+		}
+		l.Tiles = append(l.Tiles, row.String())
+	}
+	if len(l.Tilesets) > 0 {
+		l.Sprites = gids
+	}
+
+	hasStart := false
+	for _, ly := range m.Layers {
+		if ly.Type != "objectgroup" {
+			continue
+		}
+		for _, o := range ly.Objects {
+			if err := m.addObject(l, o); err != nil {
+				return nil, fmt.Errorf("layer %q object %q: %w", ly.Name, o.Name, err)
+			}
+			hasStart = hasStart || o.Type == "player_start"
+		}
+	}
+	if !hasStart {
+		return nil, fmt.Errorf("map has no player_start object")
+	}
+	return l, nil
+}
+
+// converts object pixel rect to tile rect . always at least one tile
+func (m *tiledMap) tileRect(o object) (x, y, w, h int) {
+	x = int(o.X) / m.TileWidth
+	y = int(o.Y) / m.TileHeight
+	w = max(1, int(o.Width+float64(m.TileWidth)-1)/m.TileWidth)
+	h = max(1, int(o.Height+float64(m.TileHeight)-1)/m.TileHeight)
+	return
+}
+func (m *tiledMap) addObject(l *level.Level, o object) error {
+	x, y, w, h := m.tileRect(o)
+	switch o.Type {
+	case "player_start":
+		if err := checkProperties("player_start", o.Properties, nil); err != nil {
+			return err
+		}
+		l.PlayerStart = level.Point{X: x, Y: y}
+	case "spawn_zone":
+		if err := checkProperties("spawn_zone", o.Properties, spawnZoneProperties); err != nil {
+			return err
+		}
+		z := level.SpawnZone{X: x, Y: y, Width: w, Height: h}
+		if enemies, ok := getProperty(o.Properties, "enemies"); ok && enemies != "" {
+			for _, e := range strings.Split(enemies, ",") {
+				z.Enemies = append(z.Enemies, strings.TrimSpace(e))
+			}
+		}
+		l.SpawnZones = append(l.SpawnZones, z)
+	case "trigger":
+		if err := checkProperties("trigger", o.Properties, triggerProperties); err != nil {
+			return err
+		}
+		action, ok := getProperty(o.Properties, "action")
+		if !ok {
+			return fmt.Errorf("trigger needs an action property")
+		}
+		value, _ := getProperty(o.Properties, "value")
+		l.Triggers = append(l.Triggers, level.Trigger{X: x, Y: y, Width: w, Height: h, Action: action, Value: value})
+	case "objective":
+		if err := checkProperties("objective", o.Properties, objectiveProperties); err != nil {
+			return err
+		}
+		kind, _ := getProperty(o.Properties, "kind")
+		target, _ := getProperty(o.Properties, "target")
+		obj := level.Objective{Kind: kind}
+		if _, err := fmt.Sscan(target, &obj.Target); err != nil {
+			return fmt.Errorf("objective target %q is not a number", target)
+		}
+		l.Objectives = append(l.Objectives, obj)
+	default:
+		return fmt.Errorf("unknown object type %q (allowed: player_start, spawn_zone, trigger, objective)", o.Type)
+	}
+	return nil
+}
+
+// terrain rules from map properties . level stays static if none are set
+func readTerrain(l *level.Level, props []property) error {
+	terrain := level.Terrain{}
+	fields := map[string]any{"regrow_delay": &terrain.RegrowDelay, "crumble_delay": &terrain.CrumbleDelay,
+		"crumble_neighbours": &terrain.CrumbleNeighbours, "transition": &terrain.Transition}
+	for name, field := range fields {
+		v, ok := getProperty(props, name)
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Sscan(v, field); err != nil {
+			return fmt.Errorf("map property %v %q is not a number", name, v)
+		}
+		l.Terrain = &terrain
+	}
+	return nil
+}
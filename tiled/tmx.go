@@ -0,0 +1,236 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// layout of .tmx maps and .tsx tilesets
+type tmxMap struct {
+	Width       int           `xml:"width,attr"`
+	Height      int           `xml:"height,attr"`
+	TileWidth   int           `xml:"tilewidth,attr"`
+	TileHeight  int           `xml:"tileheight,attr"`
+	Infinite    int           `xml:"infinite,attr"`
+	Orientation string        `xml:"orientation,attr"`
+	Properties  []tmxProperty `xml:"properties>property"`
+	Tilesets    []tmxTileset  `xml:"tileset"`
+	tmxGroup
+}
+
+// layers in document order . groups can hold more layers
+type tmxGroup struct {
+	Layers []tmxLayer `xml:",any"`
+}
+type tmxLayer struct {
+	XMLName xml.Name
+	Name    string      `xml:"name,attr"`
+	Data    tmxData     `xml:"data"`
+	Objects []tmxObject `xml:"object"`
+	tmxGroup
+}
+type tmxData struct {
+	Encoding    string `xml:"encoding,attr"`
+	Compression string `xml:"compression,attr"`
+	Text        string `xml:",chardata"`
+	Tiles       []struct {
+		GID int `xml:"gid,attr"`
+	} `xml:"tile"`
+}
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"` //multiline strings are stored as text
+}
+type tmxTileset struct {
+	FirstGID   int    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Columns    int    `xml:"columns,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Image      struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	Tiles []struct {
+		ID         int           `xml:"id,attr"`
+		Properties []tmxProperty `xml:"properties>property"`
+	} `xml:"tile"`
+}
+type tmxObject struct {
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr"`
+	Height     float64       `xml:"height,attr"`
+	Point      *struct{}     `xml:"point"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+func readTMX(path string) (*tiledMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tm tmxMap
+	if err := xml.Unmarshal(data, &tm); err != nil {
+		return nil, err
+	}
+	m := &tiledMap{Width: tm.Width, Height: tm.Height, TileWidth: tm.TileWidth, TileHeight: tm.TileHeight,
+		Infinite: tm.Infinite != 0, Orientation: tm.Orientation, Properties: tmxProperties(tm.Properties)}
+	for _, tts := range tm.Tilesets {
+		ts := tmxToTileset(tts, "")
+		if tts.Source != "" {
+			if ts, err = readTSX(filepath.Dir(path), tts.Source); err != nil {
+				return nil, err
+			}
+		}
+		ts.FirstGID = tts.FirstGID
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+	if err := m.addTMXLayers(tm.Layers); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reads external tileset at dir/source . image path is made relative to dir
+func readTSX(dir, source string) (tileset, error) {
+	path := filepath.Join(dir, source)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tileset{}, err
+	}
+	var tts tmxTileset
+	if err := xml.Unmarshal(data, &tts); err != nil {
+		return tileset{}, fmt.Errorf("tileset %v: %w", path, err)
+	}
+	return tmxToTileset(tts, filepath.Dir(source)), nil
+}
+func tmxToTileset(tts tmxTileset, imageDir string) tileset {
+	ts := tileset{TileWidth: tts.TileWidth, TileHeight: tts.TileHeight, Columns: tts.Columns, Count: tts.TileCount}
+	if tts.Image.Source != "" {
+		ts.Image = filepath.Join(imageDir, tts.Image.Source)
+	}
+	for _, t := range tts.Tiles {
+		ts.Tiles = append(ts.Tiles, tilesetTile{ID: t.ID, Properties: tmxProperties(t.Properties)})
+	}
+	return ts
+}
+
+// group layers are flattened keeping their order
+func (m *tiledMap) addTMXLayers(layers []tmxLayer) error {
+	for _, tl := range layers {
+		switch tl.XMLName.Local {
+		case "group":
+			if err := m.addTMXLayers(tl.Layers); err != nil {
+				return err
+			}
+		case "layer":
+			gids, err := tmxLayerData(tl.Data)
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", tl.Name, err)
+			}
+			m.Layers = append(m.Layers, layer{Name: tl.Name, Type: "tilelayer", Data: gids})
+		case "objectgroup":
+			l := layer{Name: tl.Name, Type: "objectgroup"}
+			for _, o := range tl.Objects {
+				typ := o.Type
+				if typ == "" {
+					typ = o.Class
+				}
+				l.Objects = append(l.Objects, object{Name: o.Name, Type: typ, X: o.X, Y: o.Y, Width: o.Width, Height: o.Height,
+					Point: o.Point != nil, Properties: tmxProperties(o.Properties)})
+			}
+			m.Layers = append(m.Layers, l)
+		}
+	}
+	return nil
+}
+
+func tmxLayerData(d tmxData) ([]int, error) {
+	switch d.Encoding {
+	case "csv":
+		var gids []int
+		for _, s := range strings.Split(d.Text, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("bad csv tile %q", s)
+			}
+			gids = append(gids, int(gid))
+		}
+		return gids, nil
+	case "base64":
+		return decodeBase64(strings.TrimSpace(d.Text), d.Compression)
+	case "":
+		//old xml format with one element per tile
+		gids := make([]int, len(d.Tiles))
+		for i, t := range d.Tiles {
+			gids[i] = t.GID
+		}
+		return gids, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", d.Encoding)
+}
+
+// base64 data is little endian uint32 gids . optionally gzip or zlib compressed
+func decodeBase64(s, compression string) ([]int, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = bytes.NewReader(data)
+	switch compression {
+	case "":
+	case "gzip":
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	case "zlib":
+		if r, err = zlib.NewReader(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+	if data, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("tile data length %v is not a multiple of 4", len(data))
+	}
+	gids := make([]int, len(data)/4)
+	for i := range gids {
+		gids[i] = int(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return gids, nil
+}
+
+func tmxProperties(props []tmxProperty) []property {
+	var res []property
+	for _, p := range props {
+		v := p.Value
+		if v == "" {
+			v = p.Text
+		}
+		res = append(res, property{Name: p.Name, Type: p.Type, Value: v})
+	}
+	return res
+}
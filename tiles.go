@@ -0,0 +1,166 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/utils"
+)
+
+const HAZARD_TICK = 0.5 //seconds between damage from hazard tiles
+
+// how a tile without a sprite is drawn
+type TileStyle int
+
+const (
+	FlatStyle     TileStyle = iota
+	BorderedStyle           //darker outline
+	GlowStyle               //brightness pulses over time
+)
+
+// properties shared by all tiles of a variant
+type TileType struct {
+	Name     string  //used in level files
+	Symbol   string  //character used for the variant when saving levels
+	Solid    bool    //blocks the player
+	HP       int     //hits needed to dig
+	Diggable bool    //can be dug by bullets , explosions and diggers
+	Speed    float32 //movement multiplier for things walking over it
+	Damage   int     //damage to players every HAZARD_TICK while standing on it . enemies arent hurt so they can chase through
+	Drops    int     //mana pickups dropped when dug
+	Color    color.Color
+	Style    TileStyle
+	//sheet of sprites chosen by neighbours . drawn instead of Color when loaded
+	Autotile     string
+	AutotileBits int //4 or 8
+}
+
+const (
+	Bedrock Variant = iota + Air + 1
+	Stone
+	Sludge
+	Crystal
+	Lava
+)
+
+var tileTypes = map[Variant]TileType{
+	Rigid: {Name: "rigid", Symbol: "#", Solid: true, HP: 1, Diggable: true, Speed: 1, Color: color.Black,
+		Autotile: "./tilesets/rigid_blob.png", AutotileBits: 8},
+	Air: {Name: "air", Symbol: ".", Speed: 1, Color: color.White},
+	Bedrock: {Name: "bedrock", Symbol: "B", Solid: true, HP: 1, Speed: 1, Color: color.RGBA{40, 40, 55, 255}, Style: BorderedStyle,
+		Autotile: "./tilesets/bedrock_4bit.png", AutotileBits: 4},
+	Stone:   {Name: "stone", Symbol: "S", Solid: true, HP: 3, Diggable: true, Speed: 1, Color: color.RGBA{90, 90, 90, 255}, Style: BorderedStyle},
+	Sludge:  {Name: "sludge", Symbol: "~", Speed: 0.5, Color: color.RGBA{110, 140, 60, 255}},
+	Crystal: {Name: "crystal", Symbol: "*", Solid: true, HP: 2, Diggable: true, Speed: 1, Drops: 1, Color: color.RGBA{80, 200, 255, 255}, Style: GlowStyle},
+	Lava:    {Name: "lava", Symbol: "^", Speed: 0.75, Damage: 5, Color: color.RGBA{255, 80, 0, 255}, Style: GlowStyle},
+}
+
+// names used for tile variants in level files
+var variantNames = func() map[string]Variant {
+	names := make(map[string]Variant)
+	for v, tt := range tileTypes {
+		names[tt.Name] = v
+	}
+	return names
+}()
+
+func (v Variant) Props() TileType {
+	return tileTypes[v]
+}
+
+// tile at column x and row y that starts as v without animating
+func newTile(x, y int, v Variant) *Tile {
+	return &Tile{
+		Variant:       v,
+		X:             float32(x*TILE_SIZE) + float32(x)*float32(SPACING),
+		Y:             float32(y*TILE_SIZE) + float32(y)*float32(SPACING),
+		hp:            v.Props().HP,
+		spriteVariant: v,
+		prev:          v,
+		changedAt:     math.MinInt32,
+	}
+}
+
+// changes the variant , restores its hp and starts the change animation
+func (t *Tile) SetVariant(v Variant) {
+	if t.Variant == v {
+		return
+	}
+	t.prev = t.Variant
+	t.changedAt = game.frame
+	game.Tilemap.markDirty(t)
+	game.Tilemap.markModified(t)
+	t.Variant = v
+	t.hp = v.Props().HP
+	t.regrows = false
+	t.isolated = false
+}
+func (t Tile) Solid() bool {
+	return t.Props().Solid
+}
+
+// removes n hp from a diggable tile and turns it to air when it runs out
+// returns true if the tile was dug out
+func (t *Tile) Dig(n int) bool {
+	if !t.Props().Diggable {
+		return false
+	}
+	t.hp -= n
+	if t.hp > 0 {
+		game.Tilemap.markDirty(t)
+		game.Tilemap.markModified(t)
+		return false
+	}
+	for range t.Props().Drops {
+		NewPickup(ManaPickup, utils.Vec2{X: t.X + TILE_SIZE/2 - PICKUP_SIZE/2, Y: t.Y + TILE_SIZE/2 - PICKUP_SIZE/2})
+	}
+	dug := t.Variant
+	t.SetVariant(Air)
+	t.regrows = true
+	t.regrowAs = dug
+	//crystals grow back without mana
+	if dug == Crystal {
+		t.regrowAs = Rigid
+	}
+	return true
+}
+
+// digs the tile no matter how much hp it has left
+func (t *Tile) Break() bool {
+	return t.Dig(t.hp)
+}
+
+func (t Tile) rect() utils.Rect {
+	return utils.NewRect(int(t.X), int(t.Y), TILE_SIZE, TILE_SIZE)
+}
+
+// draws the tile at x,y of dst with its variant colors when it has no sprite
+func (t Tile) drawStyled(dst *ebiten.Image, geoM ebiten.GeoM, x, y float32) {
+	props := t.Props()
+	c := props.Color
+	if props.Style == GlowStyle {
+		r, g, b, a := c.RGBA()
+		k := 0.8 + 0.2*math.Sin(float64(game.frame)/10+float64(t.X+t.Y)/50)
+		c = color.RGBA{uint8(float64(r>>8) * k), uint8(float64(g>>8) * k), uint8(float64(b>>8) * k), uint8(a >> 8)}
+	}
+	utils.FillRect(dst, geoM, x, y, TILE_SIZE, TILE_SIZE, c)
+	if props.Style == BorderedStyle {
+		utils.StrokeRect(dst, geoM, x+1, y+1, TILE_SIZE-2, TILE_SIZE-2, 2, color.RGBA{20, 20, 20, 255})
+	}
+	//cracks show missing hp
+	if props.HP > 1 && t.hp < props.HP {
+		for i := range props.HP - t.hp {
+			off := float32(6 + i*8)
+			utils.StrokeLine(dst, geoM, x+off, y+4, x+off+6, y+TILE_SIZE-4, 1, color.RGBA{20, 20, 20, 255})
+		}
+	}
+}
+
+// movement multiplier of the tile under pos
+func speedAt(pos utils.Vec2) float32 {
+	if tile := game.Tilemap.GetTile(pos); tile != nil {
+		return tile.Props().Speed
+	}
+	return 1
+}
@@ -6,7 +6,6 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hasona23/game/utils"
 )
 
@@ -119,9 +118,9 @@ func (b *Bar) SetBarColor(c color.Color) {
 // note: img is rectangle bar if img is nil
 func (b *Bar) Draw(screen *ebiten.Image) {
 	if b.img == nil {
-		b.drawRectBar(screen, utils.Point{X: 0, Y: 0})
+		b.drawRectBar(screen, ebiten.GeoM{})
 	} else {
-		b.drawImageBar(screen, utils.Point{X: 0, Y: 0})
+		b.drawImageBar(screen, ebiten.GeoM{})
 	}
 }
 
@@ -130,30 +129,31 @@ func (b *Bar) Draw(screen *ebiten.Image) {
 // note: img is rectangle bar if img is nil
 func (b *Bar) DrawCam(screen *ebiten.Image, cam utils.Cam) {
 	if b.img == nil {
-		b.drawRectBar(screen, utils.Point{X: int(cam.X), Y: int(cam.Y)})
+		b.drawRectBar(screen, cam.GeoM())
 	} else {
-		b.drawImageBar(screen, utils.Point{X: int(cam.X), Y: int(cam.Y)})
+		b.drawImageBar(screen, cam.GeoM())
 	}
 }
 
 // draws image of bar as rect .
 // used when bar img is nil
-func (b *Bar) drawRectBar(screen *ebiten.Image, offset utils.Point) {
-	x := float32(b.rect.X/b.Scale.X + offset.X)
-	y := float32(b.rect.Y/b.Scale.Y + offset.Y)
+func (b *Bar) drawRectBar(screen *ebiten.Image, geoM ebiten.GeoM) {
+	x := float32(b.rect.X / b.Scale.X)
+	y := float32(b.rect.Y / b.Scale.Y)
 	width := float32(b.rect.Width)
 	height := float32(b.rect.Height)
 
-	vector.DrawFilledRect(screen, x, y, width, height, b.BackColor, false)
-	vector.DrawFilledRect(screen, x, y, width*float32(b.GetRatio()), height, b.BarColor, false)
+	utils.FillRect(screen, geoM, x, y, width, height, b.BackColor)
+	utils.FillRect(screen, geoM, x, y, width*float32(b.GetRatio()), height, b.BarColor)
 }
 
 // draws the image of bar
 // used when img not nil
-func (b *Bar) drawImageBar(screen *ebiten.Image, offset utils.Point) {
+func (b *Bar) drawImageBar(screen *ebiten.Image, geoM ebiten.GeoM) {
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Scale(float64(b.Scale.X), float64(b.Scale.Y))
-	op.GeoM.Translate(float64(b.rect.X/b.Scale.X)+float64(offset.X), float64(b.rect.Y/b.Scale.Y)+float64(offset.Y))
+	op.GeoM.Translate(float64(b.rect.X/b.Scale.X), float64(b.rect.Y/b.Scale.Y))
+	op.GeoM.Concat(geoM)
 
 	op.ColorScale.ScaleWithColor(b.BackColor)
 	screen.DrawImage(b.img, op)
@@ -7,7 +7,6 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hasona23/game/utils"
 )
 
@@ -144,44 +143,47 @@ func (b *Button) UpdateRect() {
 		b.rect = utils.NewRect(int(b.Style.Pos.X), int(b.Style.Pos.Y), int(width)*b.Style.Scale, int(height)*b.Style.Scale)
 	}
 }
-func (b *Button) draw(screen *ebiten.Image, offset utils.Vec2) {
-	b.drawButtonWithOffset(screen, offset)
-	b.drawButtonTextWithOffset(screen, offset)
+func (b *Button) draw(screen *ebiten.Image, geoM ebiten.GeoM) {
+	b.drawButton(screen, geoM)
+	b.drawButtonText(screen, geoM)
 }
 
 func (b *Button) Draw(screen *ebiten.Image) {
-	b.draw(screen, utils.Vec2{X: 0, Y: 0})
+	b.draw(screen, ebiten.GeoM{})
 }
 
+// draws the button in the world through the camera
 func (b *Button) DrawCam(screen *ebiten.Image, cam utils.Cam) {
-	b.draw(screen, utils.Vec2{X: cam.X, Y: cam.Y})
+	b.draw(screen, cam.GeoM())
 }
 
-func (b *Button) drawButtonWithOffset(screen *ebiten.Image, offset utils.Vec2) {
+func (b *Button) drawButton(screen *ebiten.Image, geoM ebiten.GeoM) {
 	if b.sprite != nil {
 		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(math.Round(float64(b.rect.X/(b.Style.Scale))+float64(offset.X)), math.Round(float64(b.rect.X/int(b.Style.Scale))+float64(offset.Y)))
+		op.GeoM.Translate(math.Round(float64(b.rect.X/(b.Style.Scale))), math.Round(float64(b.rect.X/int(b.Style.Scale))))
 		op.GeoM.Scale(float64(b.Style.Scale), float64(b.Style.Scale))
+		op.GeoM.Concat(geoM)
 		//op.ColorScale.ScaleWithColor(b.Style.Color)
 		screen.DrawImage(b.sprite, op)
 	} else {
-		vector.DrawFilledRect(screen, float32(math.Round(float64(b.rect.X/(b.Style.Scale))+float64(offset.X))), float32(math.Round(float64(b.rect.X/(b.Style.Scale))+float64(offset.Y))), float32(b.rect.Width), float32(b.rect.Height), b.Style.BackColor, false)
-		vector.StrokeRect(screen, float32(b.rect.X)+offset.X, float32(b.rect.Y)+offset.Y, float32((b.rect.Width + b.Style.BorderThickness/2)),
+		utils.FillRect(screen, geoM, float32(math.Round(float64(b.rect.X/(b.Style.Scale)))), float32(math.Round(float64(b.rect.X/(b.Style.Scale)))), float32(b.rect.Width), float32(b.rect.Height), b.Style.BackColor)
+		utils.StrokeRect(screen, geoM, float32(b.rect.X), float32(b.rect.Y), float32((b.rect.Width + b.Style.BorderThickness/2)),
 			float32(b.rect.Height+b.Style.BorderThickness/2),
 			float32(b.Style.BorderThickness),
-			b.Style.BorderColor, false)
+			b.Style.BorderColor)
 	}
 }
 
-func (b *Button) drawButtonTextWithOffset(screen *ebiten.Image, offset utils.Vec2) {
+func (b *Button) drawButtonText(screen *ebiten.Image, geoM ebiten.GeoM) {
 	opText := &text.DrawOptions{}
 	f := &text.GoTextFace{Source: b.Text.Style.Font, Size: float64(b.Text.Style.Size)}
 	width, height := text.Measure(b.Text.Text, f, 1)
 
-	opText.GeoM.Translate(float64(float32(b.rect.X)+offset.X), float64(float32(b.rect.Y)+offset.Y))
+	opText.GeoM.Translate(float64(b.rect.X), float64(b.rect.Y))
 	if b.Style.Scale != 1 {
 		b.applyTextOrientation(opText, width, height)
 	}
+	opText.GeoM.Concat(geoM)
 	opText.ColorScale.ScaleWithColor(b.Text.Style.Color)
 	text.Draw(screen, b.Text.Text, f, opText)
 	b.UpdateRect()
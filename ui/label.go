@@ -73,7 +73,8 @@ func (l *Label) Draw(screen *ebiten.Image) {
 }
 func (l *Label) DrawCam(screen *ebiten.Image, cam utils.Cam) {
 	op := &text.DrawOptions{}
-	op.GeoM.Translate(float64(l.Pos.X+cam.X), float64(l.Pos.Y)+float64(cam.Y))
+	op.GeoM.Translate(float64(l.Pos.X), float64(l.Pos.Y))
+	op.GeoM.Concat(cam.GeoM())
 	op.ColorScale.ScaleWithColor(l.Style.Color)
 	text.Draw(screen, l.Text, l.Style.Face(), op)
 }
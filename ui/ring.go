@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/utils"
+)
+
+// segments used to draw a full ring
+const RingSegments = 24
+
+// circular progress ui such as cooldowns
+type Ring struct {
+	Pos       utils.Vec2 //centre of ring
+	Radius    float32
+	Thickness float32
+	ratio     float64
+	RingStyle
+}
+
+// controls visual aspects of ring
+type RingStyle struct {
+	RingColor color.Color //color of the filled part
+	BackColor color.Color //color of the empty part
+}
+
+func NewRing(x, y, radius, thickness float32, ringColor, backColor color.Color) *Ring {
+	return &Ring{Pos: utils.Vec2{X: x, Y: y}, Radius: radius, Thickness: thickness, ratio: 1,
+		RingStyle: RingStyle{RingColor: ringColor, BackColor: backColor}}
+}
+
+// changes how much of the ring is filled . ratio is clamped between 0 and 1
+func (r *Ring) SetRatio(ratio float64) {
+	r.ratio = math.Max(0, math.Min(1, ratio))
+}
+func (r *Ring) GetRatio() float64 {
+	return r.ratio
+}
+func (r *Ring) IsFull() bool {
+	return r.ratio == 1
+}
+func (r *Ring) SetRingColor(c color.Color) {
+	r.RingColor = c
+}
+
+// draws ring in a fixed position
+func (r *Ring) Draw(screen *ebiten.Image) {
+	r.draw(screen, ebiten.GeoM{})
+}
+
+// draws ring relative to the camera
+func (r *Ring) DrawCam(screen *ebiten.Image, cam utils.Cam) {
+	r.draw(screen, cam.GeoM())
+}
+
+// filled part starts from the top and goes clockwise
+func (r *Ring) draw(screen *ebiten.Image, geoM ebiten.GeoM) {
+	x, y := r.Pos.X, r.Pos.Y
+	utils.StrokeCircle(screen, geoM, x, y, r.Radius, r.Thickness, r.BackColor)
+	filled := int(math.Round(r.ratio * RingSegments))
+	for i := range filled {
+		a1 := -math.Pi/2 + 2*math.Pi*float64(i)/RingSegments
+		a2 := -math.Pi/2 + 2*math.Pi*float64(i+1)/RingSegments
+		utils.StrokeLine(screen, geoM, x+r.Radius*float32(math.Cos(a1)), y+r.Radius*float32(math.Sin(a1)),
+			x+r.Radius*float32(math.Cos(a2)), y+r.Radius*float32(math.Sin(a2)), r.Thickness, r.RingColor)
+	}
+}
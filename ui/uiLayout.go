@@ -13,6 +13,7 @@ type UILayout struct {
 	labels        map[string]*Label
 	buttons       map[string]*Button
 	bars          map[string]*Bar
+	rings         map[string]*Ring
 	focusedButton string
 }
 
@@ -22,6 +23,7 @@ func NewUILayout(id string) *UILayout {
 		labels:  make(map[string]*Label),
 		buttons: make(map[string]*Button),
 		bars:    make(map[string]*Bar),
+		rings:   make(map[string]*Ring),
 	}
 }
 
@@ -41,6 +43,9 @@ func (u *UILayout) AddBar(name string, bar *Bar) {
 func (u *UILayout) AddLabel(name string, label *Label) {
 	u.labels[name] = label
 }
+func (u *UILayout) AddRing(name string, ring *Ring) {
+	u.rings[name] = ring
+}
 func (u *UILayout) RemoveButton(name string) {
 	delete(u.buttons, name)
 }
@@ -50,6 +55,9 @@ func (u *UILayout) RemoveLabel(name string) {
 func (u *UILayout) RemoveBar(name string) {
 	delete(u.bars, name)
 }
+func (u *UILayout) RemoveRing(name string) {
+	delete(u.rings, name)
+}
 func (u UILayout) GetButton(name string) (*Button, bool) {
 	return u.buttons[name], u.HasButton(name)
 }
@@ -59,6 +67,13 @@ func (u UILayout) GetLabel(name string) (*Label, bool) {
 func (u UILayout) GetBar(name string) (*Bar, bool) {
 	return u.bars[name], u.HasBar(name)
 }
+func (u UILayout) GetRing(name string) (*Ring, bool) {
+	return u.rings[name], u.HasRing(name)
+}
+func (u UILayout) HasRing(name string) bool {
+	_, exist := u.rings[name]
+	return exist
+}
 func (u UILayout) HasBar(name string) bool {
 	_, exist := u.bars[name]
 	return exist
@@ -101,6 +116,7 @@ func (u *UILayout) Clear() {
 	u.buttons = make(map[string]*Button)
 	u.labels = make(map[string]*Label)
 	u.bars = make(map[string]*Bar)
+	u.rings = make(map[string]*Ring)
 	u.focusedButton = ""
 }
 func (u *UILayout) GetFocusedButton() string {
@@ -170,6 +186,9 @@ func (u *UILayout) Draw(screen *ebiten.Image) {
 	for _, bar := range u.bars {
 		bar.Draw(screen)
 	}
+	for _, ring := range u.rings {
+		ring.Draw(screen)
+	}
 }
 func (u *UILayout) DrawCam(screen *ebiten.Image, cam utils.Cam) {
 	for _, button := range u.buttons {
@@ -181,6 +200,9 @@ func (u *UILayout) DrawCam(screen *ebiten.Image, cam utils.Cam) {
 	for _, bar := range u.bars {
 		bar.DrawCam(screen, cam)
 	}
+	for _, ring := range u.rings {
+		ring.DrawCam(screen, cam)
+	}
 }
 func (u *UILayout) ApplyHoverToAllButtons(hoverEffect func(b *Button)) {
 	for i := range u.buttons {
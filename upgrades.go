@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+
+	"github.com/hasona23/game/ui"
+)
+
+const UPGRADE_CHOICES = 3
+
+// Upgrade permanently changes the player for the rest of the run
+type Upgrade struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Effect      string  `json:"effect"`
+	Rarity      string  `json:"rarity"`
+	MaxStacks   int     `json:"max_stacks"` //times it can be picked in a run
+	Amount      float64 `json:"amount"`
+}
+
+// how likely each rarity is to be offered
+var rarityWeights = map[string]float32{
+	"common":   10,
+	"uncommon": 5,
+	"rare":     2,
+}
+var rarityColors = map[string]color.RGBA{
+	"common":   {200, 200, 200, 255},
+	"uncommon": {0, 191, 255, 255},
+	"rare":     {255, 215, 0, 255},
+}
+
+// effects upgrades can have . amount comes from the upgrade data
+var upgradeEffects = map[string]func(p *Player, amount float64){
+	"max_hp": func(p *Player, amount float64) {
+		p.maxHp += int(amount)
+		p.hp += int(amount)
+	},
+	"fire_rate": func(p *Player, amount float64) {
+		for _, w := range p.weapons {
+			w.Stats().FireRate *= float32(1 - amount)
+		}
+	},
+	"projectile": func(p *Player, amount float64) {
+		for _, w := range p.weapons {
+			w.Stats().Projectiles += int(amount)
+			//spread bullets so they dont overlap
+			w.Stats().Spread = math.Max(w.Stats().Spread, 15)
+		}
+	},
+	"pierce": func(p *Player, amount float64) {
+		for _, w := range p.weapons {
+			w.Stats().Pierce += int(amount)
+		}
+	},
+	"mana_regen": func(p *Player, amount float64) {
+		p.manaRegen += float32(amount)
+	},
+}
+
+var upgrades []Upgrade
+
+// loads upgrades from json file and checks they are valid
+func LoadUpgrades(path string) ([]Upgrade, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Upgrade
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("upgrades %v: %w", path, err)
+	}
+	for _, u := range list {
+		if _, ok := upgradeEffects[u.Effect]; !ok {
+			return nil, fmt.Errorf("upgrades %v: %v: unknown effect %q", path, u.ID, u.Effect)
+		}
+		if _, ok := rarityWeights[u.Rarity]; !ok {
+			return nil, fmt.Errorf("upgrades %v: %v: unknown rarity %q", path, u.ID, u.Rarity)
+		}
+		if u.MaxStacks < 1 {
+			return nil, fmt.Errorf("upgrades %v: %v: max_stacks must be at least 1", path, u.ID)
+		}
+	}
+	return list, nil
+}
+
+func (u Upgrade) Apply(p *Player) {
+	upgradeEffects[u.Effect](p, u.Amount)
+	p.upgrades[u.ID]++
+}
+
+// picks up to n different upgrades the player can still stack based on rarity
+func rollUpgrades(p *Player, n int) []Upgrade {
+	pool := slices.DeleteFunc(slices.Clone(upgrades), func(u Upgrade) bool {
+		return p.upgrades[u.ID] >= u.MaxStacks
+	})
+	choices := []Upgrade{}
+	for len(choices) < n && len(pool) > 0 {
+		total := float32(0)
+		for _, u := range pool {
+			total += rarityWeights[u.Rarity]
+		}
+		r := rand.Float32() * total
+		for i, u := range pool {
+			if r < rarityWeights[u.Rarity] || i == len(pool)-1 {
+				choices = append(choices, u)
+				pool = slices.Delete(pool, i, i+1)
+				break
+			}
+			r -= rarityWeights[u.Rarity]
+		}
+	}
+	return choices
+}
+
+// pauses the game and shows the upgrade screen
+// upgrades are rolled for the first player and the chosen one is given to every player
+func (g *Game) offerUpgrades() {
+	choices := rollUpgrades(g.players()[0], UPGRADE_CHOICES)
+	if len(choices) == 0 {
+		return
+	}
+	//nobody can click on a server so it picks for everyone
+	if g.server != nil {
+		u := choices[rand.IntN(len(choices))]
+		for _, p := range g.players() {
+			u.Apply(p)
+		}
+		g.showBanner(u.Name, 2)
+		return
+	}
+	layout := ui.NewUILayout("upgrade")
+	title := ui.NewLabel("Choose an upgrade", 160, 40, g.font, 16, color.White)
+	title.CenterText()
+	layout.AddLabel("title", title)
+	for i, u := range choices {
+		btn := ui.NewButton(fmt.Sprintf("%v: %v", u.Name, u.Description), 20, float32(70+i*40), 10, 1, g.font,
+			rarityColors[u.Rarity], color.Black, color.Black)
+		btn.AddClickEvent(func(b *ui.Button) {
+			for _, p := range g.players() {
+				u.Apply(p)
+			}
+			g.state = States.Main
+		})
+		layout.AddButton(fmt.Sprintf("upgrade%v", i), btn)
+	}
+	layout.ApplyHoverToAllButtons(onhover)
+	g.ui[States.Upgrade] = layout
+	g.state = States.Upgrade
+}
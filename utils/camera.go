@@ -1,53 +1,174 @@
 package utils
 
 import (
+	"image/color"
 	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	MIN_ZOOM = 0.5
+	MAX_ZOOM = 2
 )
 
+// Cam is the view of the world . world positions are turned into screen positions by GeoM
+// which is worked out in Update from the followed position , zoom , rotation and shake
 type Cam struct {
-	X, Y float32
+	Width, Height float32 //screen size in pixels
+	Zoom          float32 //world pixels are drawn this many times bigger
+	Rotation      float64 //radians the world is turned around the centre of the screen
+	FollowRate    float32 //how fast the camera catches up . covers 1-e^(-rate*seconds) of the distance
+	ZoomRate      float32 //same as FollowRate for zoom
+	Deadzone      Vec2    //half size of the box around the centre the target moves in without the camera following
+	LookAhead     float32 //0-1 share of the way to the aim point the camera leans towards
+	MaxShake      float32 //pixels of offset at full trauma
+	MaxShakeAngle float64 //radians of rotation at full trauma
+	TraumaDecay   float32 //trauma lost per second
+	pos           Vec2    //world position at the centre of the view without shake
+	targetZoom    float32
+	trauma        float32 //0-1 . shake grows with its square so small hits barely move the view
+	time          float32
+	shake         Vec2 //screen offset of the current shake
+	shakeAngle    float64
+	geoM          ebiten.GeoM
 }
 
+// camera with the world position x,y at the centre of the screen
 func NewCamera(x, y float32) *Cam {
-	return &Cam{
-		X: x,
-		Y: y,
+	c := &Cam{
+		Width:         320,
+		Height:        240,
+		Zoom:          1,
+		FollowRate:    8,
+		ZoomRate:      3,
+		MaxShake:      6,
+		MaxShakeAngle: 0.03,
+		TraumaDecay:   1.5,
+		pos:           Vec2{X: x, Y: y},
+		targetZoom:    1,
 	}
+	c.Update()
+	return c
 }
 
-// ShakeAmount is how many times you want the shaking to occur
-/*func (c *Camera) Shake(strengh float32, ShakeAmount int) {
-	//factors is between  -1 , 1 to maniplutate direction of x and y displacement
-	factor := 0
-	for range ShakeAmount {
-		if rand.Int()%2 == 0 {
-			factor = 1
-		} else {
-			factor = -1
-		}
-		x := rand.Float32() * strengh * float32(factor)
-		y := rand.Float32() * strengh * float32(factor)
-		c.X += int(x)
-		c.Y += int(y)
+// moves smoothly towards target leaning LookAhead of the way to aim
+// the camera stays still while the target is inside the deadzone
+func (c *Cam) Follow(target, aim Vec2) {
+	goal := Vec2{X: target.X + (aim.X-target.X)*c.LookAhead, Y: target.Y + (aim.Y-target.Y)*c.LookAhead}
+	goal.X = c.pos.X + outside(goal.X-c.pos.X, c.Deadzone.X)
+	goal.Y = c.pos.Y + outside(goal.Y-c.pos.Y, c.Deadzone.Y)
+	t := c.ease(c.FollowRate)
+	c.pos.X += (goal.X - c.pos.X) * t
+	c.pos.Y += (goal.Y - c.pos.Y) * t
+}
+
+// how far d is past the edge of a box of half size r
+func outside(d, r float32) float32 {
+	if d > r {
+		return d - r
+	} else if d < -r {
+		return d + r
 	}
-}*/
+	return 0
+}
 
-// For Smooth Movement
-func (c *Cam) FollowTarget(targetX, targetY, screenWidth, screenHeight, speed float32) {
-	c.X += (-targetX + screenWidth/2.0 - c.X) / speed
-	c.Y += (-targetY + screenHeight/2.0 - c.Y) / speed
+// share of the distance covered this frame when catching up at rate
+func (c *Cam) ease(rate float32) float32 {
+	return 1 - float32(math.Exp(-float64(rate)/float64(ebiten.TPS())))
 }
 
 // For Sudden and fast without delay movemnt
-func (c *Cam) GoTo(targetX, targetY, screenWidth, screenHeight float32) {
-	c.X = (-targetX + screenWidth/2.0)
-	c.Y = (-targetY + screenHeight/2.0)
+func (c *Cam) GoTo(targetX, targetY float32) {
+	c.pos = Vec2{X: targetX, Y: targetY}
+}
+
+// keeps the view inside the map . maps smaller than the view are centered
+func (c *Cam) Constrain(tilemapWidthPixels, tilemapHeightPixels int) {
+	w, h := c.ViewSize()
+	c.pos.X = clampView(c.pos.X, w, float32(tilemapWidthPixels))
+	c.pos.Y = clampView(c.pos.Y, h, float32(tilemapHeightPixels))
+}
+func clampView(centre, view, size float32) float32 {
+	if view >= size {
+		return size / 2
+	}
+	return float32(math.Max(float64(view/2), math.Min(float64(centre), float64(size-view/2))))
 }
 
-func (c *Cam) Constrain(tilemapWidthPixels, tilemapHeightPixels, screenWidth, screenHeight int) {
-	c.X = float32(math.Min(float64(c.X), 0.0))
-	c.Y = float32(math.Min(float64(c.Y), 0.0))
+// size of the world in view at the current zoom
+func (c *Cam) ViewSize() (float32, float32) {
+	return c.Width / c.Zoom, c.Height / c.Zoom
+}
 
-	c.X = float32(math.Max(float64(c.X), float64(screenWidth-tilemapWidthPixels)))
-	c.Y = float32(math.Max(float64(c.Y), float64(screenHeight-tilemapHeightPixels)))
+// adds trauma from 0 to 1 . explosions add a lot and small hits a little
+func (c *Cam) Shake(trauma float32) {
+	c.trauma = float32(math.Min(1, float64(c.trauma+trauma)))
+}
+
+// zooms smoothly to zoom
+func (c *Cam) SetZoom(zoom float32) {
+	c.targetZoom = float32(math.Max(MIN_ZOOM, math.Min(MAX_ZOOM, float64(zoom))))
+}
+
+// eases zoom , wears off trauma and works out GeoM . call once a frame after Follow and Constrain
+func (c *Cam) Update() {
+	dt := 1 / float32(ebiten.TPS())
+	c.time += dt
+	c.Zoom += (c.targetZoom - c.Zoom) * c.ease(c.ZoomRate)
+	c.trauma = float32(math.Max(0, float64(c.trauma-c.TraumaDecay*dt)))
+	//sines of unrelated frequencies shake smoothly instead of jumping every frame
+	shake := c.MaxShake * c.trauma * c.trauma
+	t := float64(c.time)
+	c.shake.X = shake * float32(math.Sin(t*47)+math.Sin(t*29.3)) / 2
+	c.shake.Y = shake * float32(math.Sin(t*43.7)+math.Sin(t*31.1)) / 2
+	c.shakeAngle = c.MaxShakeAngle * float64(c.trauma*c.trauma) * (math.Sin(t*37.9) + math.Sin(t*23.1)) / 2
+	c.geoM.Reset()
+	c.geoM.Translate(-float64(c.pos.X), -float64(c.pos.Y))
+	c.geoM.Rotate(c.Rotation + c.shakeAngle)
+	c.geoM.Scale(float64(c.Zoom), float64(c.Zoom))
+	c.geoM.Translate(float64(c.Width/2+c.shake.X), float64(c.Height/2+c.shake.Y))
+}
+
+// transform from world to screen . concat it to the GeoM of images drawn in the world
+func (c *Cam) GeoM() ebiten.GeoM {
+	return c.geoM
+}
+func (c *Cam) WorldToScreen(v Vec2) Vec2 {
+	x, y := c.geoM.Apply(float64(v.X), float64(v.Y))
+	return Vec2{X: float32(x), Y: float32(y)}
+}
+func (c *Cam) ScreenToWorld(v Vec2) Vec2 {
+	inv := c.geoM
+	inv.Invert()
+	x, y := inv.Apply(float64(v.X), float64(v.Y))
+	return Vec2{X: float32(x), Y: float32(y)}
+}
+
+// smallest world rect holding everything on screen . used to skip drawing what is out of view
+func (c *Cam) ViewRect() Rect {
+	corners := []Vec2{c.ScreenToWorld(Vec2{}), c.ScreenToWorld(Vec2{X: c.Width}),
+		c.ScreenToWorld(Vec2{Y: c.Height}), c.ScreenToWorld(Vec2{X: c.Width, Y: c.Height})}
+	minX, minY := corners[0].X, corners[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range corners[1:] {
+		minX, minY = min(minX, v.X), min(minY, v.Y)
+		maxX, maxY = max(maxX, v.X), max(maxY, v.Y)
+	}
+	x, y := int(math.Floor(float64(minX))), int(math.Floor(float64(minY)))
+	return NewRect(x, y, int(math.Ceil(float64(maxX)))-x, int(math.Ceil(float64(maxY)))-y)
+}
+
+// shapes drawn in the world through the camera
+func (c *Cam) FillRect(dst *ebiten.Image, x, y, width, height float32, clr color.Color) {
+	FillRect(dst, c.geoM, x, y, width, height, clr)
+}
+func (c *Cam) StrokeRect(dst *ebiten.Image, x, y, width, height, strokeWidth float32, clr color.Color) {
+	StrokeRect(dst, c.geoM, x, y, width, height, strokeWidth, clr)
+}
+func (c *Cam) StrokeLine(dst *ebiten.Image, x0, y0, x1, y1, strokeWidth float32, clr color.Color) {
+	StrokeLine(dst, c.geoM, x0, y0, x1, y1, strokeWidth, clr)
+}
+func (c *Cam) StrokeCircle(dst *ebiten.Image, cx, cy, r, strokeWidth float32, clr color.Color) {
+	StrokeCircle(dst, c.geoM, cx, cy, r, strokeWidth, clr)
 }
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// shapes below are drawn through a GeoM so they can be moved , zoomed and rotated by a camera
+// a zero GeoM draws them where they are like the vector package
+
+var whiteImage = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img
+}()
+
+// centre pixel so edges of the image dont bleed into triangles
+var whitePixel = whiteImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+
+func FillRect(dst *ebiten.Image, geoM ebiten.GeoM, x, y, width, height float32, clr color.Color) {
+	path := &vector.Path{}
+	path.MoveTo(x, y)
+	path.LineTo(x+width, y)
+	path.LineTo(x+width, y+height)
+	path.LineTo(x, y+height)
+	path.Close()
+	fillPath(dst, geoM, path, clr)
+}
+func StrokeRect(dst *ebiten.Image, geoM ebiten.GeoM, x, y, width, height, strokeWidth float32, clr color.Color) {
+	path := &vector.Path{}
+	path.MoveTo(x, y)
+	path.LineTo(x+width, y)
+	path.LineTo(x+width, y+height)
+	path.LineTo(x, y+height)
+	path.Close()
+	strokePath(dst, geoM, path, strokeWidth, clr)
+}
+func StrokeLine(dst *ebiten.Image, geoM ebiten.GeoM, x0, y0, x1, y1, strokeWidth float32, clr color.Color) {
+	path := &vector.Path{}
+	path.MoveTo(x0, y0)
+	path.LineTo(x1, y1)
+	strokePath(dst, geoM, path, strokeWidth, clr)
+}
+func StrokeCircle(dst *ebiten.Image, geoM ebiten.GeoM, cx, cy, r, strokeWidth float32, clr color.Color) {
+	path := &vector.Path{}
+	path.Arc(cx, cy, r, 0, 2*math.Pi, vector.Clockwise)
+	path.Close()
+	strokePath(dst, geoM, path, strokeWidth, clr)
+}
+
+func fillPath(dst *ebiten.Image, geoM ebiten.GeoM, path *vector.Path, clr color.Color) {
+	vs, is := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	drawVertices(dst, geoM, vs, is, clr)
+}
+
+// stroke is made in world units before the GeoM so its width zooms with the shape
+func strokePath(dst *ebiten.Image, geoM ebiten.GeoM, path *vector.Path, strokeWidth float32, clr color.Color) {
+	vs, is := path.AppendVerticesAndIndicesForStroke(nil, nil, &vector.StrokeOptions{Width: strokeWidth, LineJoin: vector.LineJoinMiter, MiterLimit: 10})
+	drawVertices(dst, geoM, vs, is, clr)
+}
+func drawVertices(dst *ebiten.Image, geoM ebiten.GeoM, vs []ebiten.Vertex, is []uint16, clr color.Color) {
+	r, g, b, a := clr.RGBA()
+	for i := range vs {
+		x, y := geoM.Apply(float64(vs[i].DstX), float64(vs[i].DstY))
+		vs[i].DstX, vs[i].DstY = float32(x), float32(y)
+		vs[i].SrcX, vs[i].SrcY = 1, 1
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+	dst.DrawTriangles(vs, is, whitePixel, &ebiten.DrawTrianglesOptions{FillRule: ebiten.FillAll})
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"image"
+
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -43,6 +45,21 @@ func NewSprite(img *ebiten.Image) *Sprite {
 	}
 	return &Sprite{img, None}
 }
+
+// splits a sheet into tileWidth*tileHeight sprites row by row
+func NewSpriteSheet(img *ebiten.Image, tileWidth, tileHeight int) []*Sprite {
+	if img == nil {
+		panic("SpriteSheet : img is nil")
+	}
+	sprites := []*Sprite{}
+	for y := 0; y+tileHeight <= img.Bounds().Dy(); y += tileHeight {
+		for x := 0; x+tileWidth <= img.Bounds().Dx(); x += tileWidth {
+			sub := img.SubImage(image.Rect(x, y, x+tileWidth, y+tileHeight).Add(img.Bounds().Min)).(*ebiten.Image)
+			sprites = append(sprites, NewSprite(sub))
+		}
+	}
+	return sprites
+}
 func (s Sprite) SetSpriteOP(op *ebiten.DrawImageOptions, rotation float32) {
 
 	switch s.Effect {
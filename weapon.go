@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hasona23/game/utils"
+)
+
+// Weapon is a gun the player can hold and switch to
+type Weapon interface {
+	Name() string
+	Stats() *WeaponStats
+	Update()
+	// fires from pos towards aim (radians). returns false if weapon couldnt fire
+	Fire(pos utils.Vec2, aim float64) bool
+	// ammo or heat text shown in the hud
+	Status() string
+}
+
+type WeaponStats struct {
+	FireRate    float32 //seconds between shots
+	Spread      float64 //degrees of random error for each projectile
+	Projectiles int     //bullets per shot
+	Speed       float32
+	Damage      int
+	Lifetime    float32 //seconds before bullets disappear . controls range
+	Dig         float32 //radius of tiles dug when hitting rigid tiles
+	Pierce      int     //enemies a bullet can go through
+	Explosion   float32 //radius of area damage when bullet is destroyed
+	MaxAmmo     int     //-1 for infinite ammo
+	HeatPerShot float32 //0 means weapon doesnt heat up
+	CoolRate    float32 //heat lost per second
+}
+
+// Gun is a weapon fully described by its stats
+type Gun struct {
+	name       string
+	stats      WeaponStats
+	fireRate   utils.Timer
+	ammo       int
+	heat       float32 //from 0 to 1
+	overheated bool
+}
+
+func NewGun(name string, stats WeaponStats) *Gun {
+	//timer starts at 0 so first shot is ready immediately
+	return &Gun{name: name, stats: stats, fireRate: utils.NewTimer(0), ammo: stats.MaxAmmo}
+}
+func NewPistol() *Gun {
+	return NewGun("Pistol", WeaponStats{FireRate: PLAYER_FIRERATE, Projectiles: 1, Speed: 2, Damage: PLAYER_DAMAGE,
+		Lifetime: BULLET_LIFETIME, MaxAmmo: -1})
+}
+
+// short range spread that digs a cone of tiles
+func NewShotgun() *Gun {
+	return NewGun("Shotgun", WeaponStats{FireRate: 1, Spread: 40, Projectiles: 6, Speed: 2.5, Damage: PLAYER_DAMAGE,
+		Lifetime: 0.6, Dig: TILE_SIZE, MaxAmmo: 24})
+}
+
+// fast bullet that goes through enemies . heats up instead of using ammo
+func NewRail() *Gun {
+	return NewGun("Rail", WeaponStats{FireRate: 0.5, Projectiles: 1, Speed: 5, Damage: 2,
+		Lifetime: BULLET_LIFETIME, Pierce: 5, MaxAmmo: -1, HeatPerShot: 0.35, CoolRate: 0.25})
+}
+
+// slow rocket that explodes damaging everything around
+func NewRocket() *Gun {
+	return NewGun("Rocket", WeaponStats{FireRate: 1.5, Projectiles: 1, Speed: 1.5, Damage: 2,
+		Lifetime: BULLET_LIFETIME, Explosion: 56, MaxAmmo: 6})
+}
+
+// weapons the player starts with in slot order
+func DefaultWeapons() []Weapon {
+	return []Weapon{NewPistol(), NewShotgun(), NewRail(), NewRocket()}
+}
+
+func (g *Gun) Name() string {
+	return g.name
+}
+func (g *Gun) Stats() *WeaponStats {
+	return &g.stats
+}
+func (g *Gun) Update() {
+	if g.fireRate.GetCurrentTime() < g.fireRate.Time {
+		g.fireRate.UpdateTimer()
+	}
+	if g.stats.HeatPerShot > 0 {
+		g.heat = float32(math.Max(0, float64(g.heat-g.stats.CoolRate/float32(ebiten.TPS()))))
+		if g.heat == 0 {
+			g.overheated = false
+		}
+	}
+}
+func (g *Gun) Fire(pos utils.Vec2, aim float64) bool {
+	if g.overheated || g.ammo == 0 || g.fireRate.GetCurrentTime() < g.fireRate.Time {
+		return false
+	}
+	g.fireRate = utils.NewTimer(g.stats.FireRate)
+	if g.ammo > 0 {
+		g.ammo--
+	}
+	if g.stats.HeatPerShot > 0 {
+		g.heat += g.stats.HeatPerShot
+		if g.heat >= 1 {
+			g.heat = 1
+			g.overheated = true
+		}
+	}
+	spread := g.stats.Spread * math.Pi / 180
+	for range g.stats.Projectiles {
+		angle := aim + (rand.Float64()*2-1)*spread/2
+		b := NewBullet("player", pos, utils.Vec2{X: float32(math.Cos(angle)), Y: float32(math.Sin(angle))}, g.stats.Speed)
+		b.damage = g.stats.Damage
+		b.lifeTime = utils.NewTimer(g.stats.Lifetime)
+		b.dig = g.stats.Dig
+		b.pierce = g.stats.Pierce
+		b.explosion = g.stats.Explosion
+	}
+	return true
+}
+
+// adds ammo up to max ammo
+func (g *Gun) AddAmmo(n int) {
+	if g.stats.MaxAmmo < 0 {
+		return
+	}
+	g.ammo = int(math.Min(float64(g.ammo+n), float64(g.stats.MaxAmmo)))
+}
+func (g *Gun) Status() string {
+	switch {
+	case g.overheated:
+		return fmt.Sprintf("%v OVERHEAT", g.name)
+	case g.stats.HeatPerShot > 0:
+		return fmt.Sprintf("%v %v%%", g.name, int(g.heat*100))
+	case g.stats.MaxAmmo >= 0:
+		return fmt.Sprintf("%v %v/%v", g.name, g.ammo, g.stats.MaxAmmo)
+	}
+	return g.name
+}